@@ -0,0 +1,89 @@
+package anim
+
+import (
+	"image/color"
+	"testing"
+	"time"
+)
+
+func TestTimersAndAnimations(t *testing.T) {
+	start := time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC)
+	clock := NewClock(start)
+
+	once, every := 0, 0
+	clock.AfterFunc(50*time.Millisecond, func() { once++ })
+	ticker := clock.Every(20*time.Millisecond, func() { every++ })
+
+	var c color.Color
+	clock.Start(TweenColor(color.Black, color.White, 100*time.Millisecond, Linear,
+		func(v color.Color) { c = v }))
+
+	for ms := 10; ms <= 100; ms += 10 {
+		clock.Tick(start.Add(time.Duration(ms) * time.Millisecond))
+		if ms == 50 {
+			got := color.NRGBAModel.Convert(c).(color.NRGBA)
+			if got.R < 120 || got.R > 135 {
+				t.Errorf("color at 50%% = %v", got)
+			}
+		}
+	}
+	ticker.Stop()
+	clock.Tick(start.Add(200 * time.Millisecond))
+
+	if once != 1 {
+		t.Errorf("AfterFunc fired %d times, want 1", once)
+	}
+	if every != 5 {
+		t.Errorf("Every fired %d times, want 5", every)
+	}
+	if got := color.NRGBAModel.Convert(c).(color.NRGBA); got.R != 255 {
+		t.Errorf("final color = %v, want white", got)
+	}
+	if clock.Elapsed() != 200*time.Millisecond || clock.Frame() != 11 {
+		t.Errorf("Elapsed() = %v, Frame() = %d", clock.Elapsed(), clock.Frame())
+	}
+
+	// Сброс останавливает таймеры
+	fired := 0
+	clock.Every(10*time.Millisecond, func() { fired++ })
+	clock.Reset(start)
+	clock.Tick(start.Add(time.Second))
+	if fired != 0 {
+		t.Errorf("timer fired %d times after Reset", fired)
+	}
+
+	if clock.Every(0, func() {}).Active() {
+		t.Error("Every(0) returned an active timer")
+	}
+}
+
+func TestWidgetTimers(t *testing.T) {
+	start := time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC)
+	clock := NewClock(start)
+	clock.Tick(start.Add(time.Second))
+
+	// Таймер, запущенный до привязки, отсчитывается от первого кадра на часах
+	var timers Timers
+	fired := 0
+	timer := timers.Every(100*time.Millisecond, func() { fired++ })
+	clock.Tick(start.Add(2 * time.Second))
+	if fired != 0 {
+		t.Fatalf("unbound timer fired %d times", fired)
+	}
+
+	timers.SetClock(clock)
+	clock.Tick(start.Add(2*time.Second + 150*time.Millisecond))
+	if fired != 1 {
+		t.Fatalf("bound timer fired %d times, want 1", fired)
+	}
+	if timers.Now() != clock.Now() {
+		t.Errorf("Now() = %v, want %v", timers.Now(), clock.Now())
+	}
+
+	// Отвязка от часов останавливает таймеры виджета
+	timers.SetClock(nil)
+	clock.Tick(start.Add(3 * time.Second))
+	if fired != 1 || timer.Active() {
+		t.Errorf("timer fired %d times after unbinding, active %v", fired, timer.Active())
+	}
+}
@@ -0,0 +1,153 @@
+package anim
+
+import (
+	"image"
+	"image/color"
+	"math"
+	"sync/atomic"
+	"time"
+)
+
+// Анимация свойства.
+// На каждом кадре вызывается OnUpdate с долей выполнения 0..1,
+// преобразованной функцией плавности. OnUpdate должен установить
+// новое значение свойства виджета, что отметит виджет измененным
+type Animation struct {
+	Duration time.Duration
+	Easing   Easing // Если не указана, то Linear
+	Loop     bool   // Повторять бесконечно
+	Reverse  bool   // При повторе идти в обратную сторону (туда-обратно)
+
+	OnUpdate func(t float64)
+	OnDone   func() // Вызывается по завершении неповторяющейся анимации
+
+	start   time.Time // Время запуска, изменяется под блокировкой часов
+	running atomic.Bool
+}
+
+// Запускает анимацию с начала по часам c
+func (c *Clock) Start(a *Animation) *Animation {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	a.start = c.now
+	a.running.Store(true)
+	for _, r := range c.animations {
+		if r == a {
+			return a
+		}
+	}
+	c.animations = append(c.animations, a)
+	return a
+}
+
+// Останавливает анимацию, свойство остается в текущем значении
+func (a *Animation) Stop() {
+	a.running.Store(false)
+}
+
+// Возвращает true, если анимация выполняется
+func (a *Animation) Running() bool {
+	return a.running.Load()
+}
+
+// Вычисляет значение для кадра now. Вызывается часами без блокировки,
+// start - время запуска, прочитанное под блокировкой часов
+func (a *Animation) step(start, now time.Time) {
+	if !a.running.Load() {
+		return
+	}
+
+	t := 1.0
+	if a.Duration > 0 {
+		t = float64(now.Sub(start)) / float64(a.Duration)
+	}
+
+	done := false
+	if a.Loop {
+		cycle := math.Floor(t)
+		t -= cycle
+		if a.Reverse && int64(cycle)%2 == 1 {
+			t = 1 - t
+		}
+	} else if t >= 1 {
+		t = 1
+		done = true
+		a.running.Store(false)
+	}
+
+	easing := a.Easing
+	if easing == nil {
+		easing = Linear
+	}
+
+	if a.OnUpdate != nil {
+		a.OnUpdate(easing(t))
+	}
+	if done && a.OnDone != nil {
+		a.OnDone()
+	}
+}
+
+// Создает анимацию числа от from до to.
+// Анимация запускается через Clock.Start или Timers.Start
+func TweenFloat(from, to float64, d time.Duration, e Easing, set func(float64)) *Animation {
+	return &Animation{
+		Duration: d,
+		Easing:   e,
+		OnUpdate: func(t float64) { set(LerpFloat(from, to, t)) },
+	}
+}
+
+// Создает анимацию цвета от from до to
+func TweenColor(from, to color.Color, d time.Duration, e Easing, set func(color.Color)) *Animation {
+	return &Animation{
+		Duration: d,
+		Easing:   e,
+		OnUpdate: func(t float64) { set(LerpColor(from, to, t)) },
+	}
+}
+
+// Создает анимацию положения от from до to
+func TweenPoint(from, to image.Point, d time.Duration, e Easing, set func(image.Point)) *Animation {
+	return &Animation{
+		Duration: d,
+		Easing:   e,
+		OnUpdate: func(t float64) { set(LerpPoint(from, to, t)) },
+	}
+}
+
+// Линейная интерполяция числа
+func LerpFloat(a, b, t float64) float64 {
+	return a + (b-a)*t
+}
+
+// Линейная интерполяция положения
+func LerpPoint(a, b image.Point, t float64) image.Point {
+	return image.Point{
+		X: int(math.Round(LerpFloat(float64(a.X), float64(b.X), t))),
+		Y: int(math.Round(LerpFloat(float64(a.Y), float64(b.Y), t))),
+	}
+}
+
+// Линейная интерполяция цвета, с учетом прозрачности.
+// nil считается полностью прозрачным цветом
+func LerpColor(a, b color.Color, t float64) color.Color {
+	ca, cb := toNRGBA(a), toNRGBA(b)
+	lerp := func(x, y uint8) uint8 {
+		return uint8(math.Round(LerpFloat(float64(x), float64(y), t)))
+	}
+	return color.NRGBA{
+		R: lerp(ca.R, cb.R),
+		G: lerp(ca.G, cb.G),
+		B: lerp(ca.B, cb.B),
+		A: lerp(ca.A, cb.A),
+	}
+}
+
+func toNRGBA(c color.Color) color.NRGBA {
+	if c == nil {
+		return color.NRGBA{}
+	}
+	return color.NRGBAModel.Convert(c).(color.NRGBA)
+}
@@ -0,0 +1,19 @@
+package anim
+
+import "time"
+
+// Возвращает фазу мигания для текущего кадра:
+// true в течение on, затем false в течение off.
+// Фаза отсчитывается от начала часов, поэтому все виджеты
+// с одинаковым периодом мигают синхронно
+func (c *Clock) Blink(on, off time.Duration) bool {
+	return blink(c.Elapsed(), on, off)
+}
+
+func blink(elapsed, on, off time.Duration) bool {
+	period := on + off
+	if on <= 0 || period <= 0 {
+		return on > 0
+	}
+	return elapsed%period < on
+}
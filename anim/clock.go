@@ -0,0 +1,135 @@
+// Часы кадров, таймеры и анимации.
+// Время часов - это время текущего кадра, оно устанавливается
+// через Tick() перед отрисовкой каждого кадра (это делает Sgui.Render
+// для своих часов). Виджеты одного Sgui работают по одним часам,
+// поэтому анимации и мигание разных виджетов идут синхронно.
+
+package anim
+
+import (
+	"sync"
+	"time"
+)
+
+// Часы кадров
+type Clock struct {
+	mu         sync.Mutex
+	start      time.Time // Время создания часов, начало отсчета фаз
+	now        time.Time // Время текущего кадра
+	frame      uint64    // Номер текущего кадра
+	timers     []*Timer
+	animations []*Animation
+}
+
+// Создает часы с временем первого кадра now
+func NewClock(now time.Time) *Clock {
+	return &Clock{
+		start: now,
+		now:   now,
+	}
+}
+
+// Начинает новый кадр со временем now.
+// Срабатывают наступившие таймеры и обновляются анимации.
+// Если now меньше времени предыдущего кадра, время не изменяется
+func (c *Clock) Tick(now time.Time) {
+	c.mu.Lock()
+	if now.After(c.now) {
+		c.now = now
+	}
+	c.frame++
+	now = c.now
+
+	// Собираем сработавшие таймеры и активные анимации.
+	// Функции вызываются без блокировки, что бы из них можно было
+	// запускать новые таймеры и анимации
+	var fired []*Timer
+	timers := c.timers[:0]
+	for _, t := range c.timers {
+		if t.stopped.Load() {
+			continue
+		}
+		if !now.Before(t.at) {
+			fired = append(fired, t)
+			if t.period > 0 {
+				for !now.Before(t.at) {
+					t.at = t.at.Add(t.period)
+				}
+			} else {
+				t.stopped.Store(true)
+				continue
+			}
+		}
+		timers = append(timers, t)
+	}
+	c.timers = timers
+
+	animations := make([]*Animation, 0, len(c.animations))
+	for _, a := range c.animations {
+		if a.running.Load() {
+			animations = append(animations, a)
+		}
+	}
+	c.animations = animations
+	starts := make([]time.Time, len(animations))
+	for i, a := range animations {
+		starts[i] = a.start
+	}
+	active := append([]*Animation(nil), animations...)
+	c.mu.Unlock()
+
+	for _, t := range fired {
+		t.f()
+	}
+
+	for i, a := range active {
+		a.step(starts[i], now)
+	}
+}
+
+// Возвращает время текущего кадра
+func (c *Clock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+// Возвращает номер текущего кадра
+func (c *Clock) Frame() uint64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.frame
+}
+
+// Возвращает время, прошедшее с момента t до текущего кадра
+func (c *Clock) Since(t time.Time) time.Duration {
+	return c.Now().Sub(t)
+}
+
+// Возвращает время от начала отсчета часов до текущего кадра.
+// Используется для вычисления фазы периодических эффектов
+func (c *Clock) Elapsed() time.Duration {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now.Sub(c.start)
+}
+
+// Переносит начало отсчета часов на время now
+// и останавливает все таймеры и анимации.
+// Предназначено для тестов, где используется подставное время
+func (c *Clock) Reset(now time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.start = now
+	c.now = now
+	c.frame = 0
+
+	for _, t := range c.timers {
+		t.stopped.Store(true)
+	}
+	for _, a := range c.animations {
+		a.running.Store(false)
+	}
+	c.timers = nil
+	c.animations = nil
+}
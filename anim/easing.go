@@ -0,0 +1,52 @@
+package anim
+
+import "math"
+
+// Функция плавности. Преобразует долю прошедшего времени 0..1
+// в долю изменения свойства
+type Easing func(t float64) float64
+
+func Linear(t float64) float64 {
+	return t
+}
+
+// Медленный старт
+func EaseIn(t float64) float64 {
+	return t * t
+}
+
+// Медленное завершение
+func EaseOut(t float64) float64 {
+	return t * (2 - t)
+}
+
+// Медленный старт и завершение
+func EaseInOut(t float64) float64 {
+	if t < 0.5 {
+		return 2 * t * t
+	}
+	return -1 + (4-2*t)*t
+}
+
+// Медленный старт и завершение по синусоиде
+func EaseInOutSine(t float64) float64 {
+	return -(math.Cos(math.Pi*t) - 1) / 2
+}
+
+// Завершение с отскоком
+func EaseOutBounce(t float64) float64 {
+	const n, d = 7.5625, 2.75
+	switch {
+	case t < 1/d:
+		return n * t * t
+	case t < 2/d:
+		t -= 1.5 / d
+		return n*t*t + 0.75
+	case t < 2.5/d:
+		t -= 2.25 / d
+		return n*t*t + 0.9375
+	default:
+		t -= 2.625 / d
+		return n*t*t + 0.984375
+	}
+}
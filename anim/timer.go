@@ -0,0 +1,60 @@
+package anim
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// Таймер, срабатывающий по времени кадров.
+// Функция таймера вызывается в горутине отрисовки, перед отрисовкой кадра
+type Timer struct {
+	at      time.Time     // Время срабатывания
+	delay   time.Duration // Задержка первого срабатывания
+	period  time.Duration // Период для повторяющегося таймера, 0 - однократный
+	f       func()
+	stopped atomic.Bool
+}
+
+// Запускает однократный таймер, вызывающий f через d
+func (c *Clock) AfterFunc(d time.Duration, f func()) *Timer {
+	t := &Timer{delay: d, f: f}
+	c.addTimer(t)
+	return t
+}
+
+// Запускает таймер, вызывающий f каждые d.
+// Если кадры отрисовываются реже, чем d, то за кадр f вызывается один раз.
+// Если d не положительный, то возвращается остановленный таймер
+func (c *Clock) Every(d time.Duration, f func()) *Timer {
+	t := newTicker(d, f)
+	if t.Active() {
+		c.addTimer(t)
+	}
+	return t
+}
+
+// Останавливает таймер
+func (t *Timer) Stop() {
+	t.stopped.Store(true)
+}
+
+// Возвращает false, если таймер остановлен или однократный таймер уже сработал
+func (t *Timer) Active() bool {
+	return !t.stopped.Load()
+}
+
+func newTicker(d time.Duration, f func()) *Timer {
+	t := &Timer{delay: d, period: d, f: f}
+	if d <= 0 {
+		t.stopped.Store(true)
+	}
+	return t
+}
+
+func (c *Clock) addTimer(t *Timer) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	t.at = c.now.Add(t.delay)
+	c.timers = append(c.timers, t)
+}
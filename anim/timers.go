@@ -0,0 +1,147 @@
+package anim
+
+import (
+	"sync"
+	"time"
+)
+
+// Таймеры и анимации одного виджета.
+// Работают по часам, к которым виджет привязан через SetClock,
+// Sgui привязывает виджеты к своим часам при отрисовке.
+// Запущенные до привязки таймеры и анимации отсчитываются от первого кадра.
+// При отвязке от часов (удалении виджета с экрана) и при смене часов
+// все таймеры и анимации останавливаются. Нулевое значение готово к работе
+type Timers struct {
+	mu         sync.Mutex
+	clock      *Clock
+	timers     []*Timer
+	animations []*Animation
+}
+
+// Привязывает к часам c. nil отвязывает от часов
+func (t *Timers) SetClock(c *Clock) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if c != nil && c == t.clock {
+		return
+	}
+	if c == nil || t.clock != nil {
+		t.stop()
+	}
+	t.clock = c
+	if c == nil {
+		return
+	}
+
+	// Запускаем ожидавшие привязки
+	for _, tm := range t.timers {
+		if tm.Active() {
+			c.addTimer(tm)
+		}
+	}
+	for _, a := range t.animations {
+		if a.Running() {
+			c.Start(a)
+		}
+	}
+}
+
+// Возвращает часы, nil - если не привязан
+func (t *Timers) Clock() *Clock {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.clock
+}
+
+// Возвращает время текущего кадра. До привязки к часам - текущее время
+func (t *Timers) Now() time.Time {
+	if c := t.Clock(); c != nil {
+		return c.Now()
+	}
+	return time.Now()
+}
+
+// Возвращает время, прошедшее с момента tm до текущего кадра
+func (t *Timers) Since(tm time.Time) time.Duration {
+	return t.Now().Sub(tm)
+}
+
+// Возвращает фазу мигания для текущего кадра, см. Clock.Blink
+func (t *Timers) Blink(on, off time.Duration) bool {
+	if c := t.Clock(); c != nil {
+		return c.Blink(on, off)
+	}
+	return blink(time.Duration(time.Now().UnixNano()), on, off)
+}
+
+// Запускает однократный таймер, вызывающий f через d
+func (t *Timers) AfterFunc(d time.Duration, f func()) *Timer {
+	return t.addTimer(&Timer{delay: d, f: f})
+}
+
+// Запускает таймер, вызывающий f каждые d, см. Clock.Every
+func (t *Timers) Every(d time.Duration, f func()) *Timer {
+	return t.addTimer(newTicker(d, f))
+}
+
+// Запускает анимацию с начала
+func (t *Timers) Start(a *Animation) *Animation {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	animations := t.animations[:0]
+	for _, r := range t.animations {
+		if r != a && r.Running() {
+			animations = append(animations, r)
+		}
+	}
+	t.animations = append(animations, a)
+
+	if t.clock != nil {
+		return t.clock.Start(a)
+	}
+	a.running.Store(true)
+	return a
+}
+
+// Останавливает все таймеры и анимации
+func (t *Timers) Stop() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.stop()
+}
+
+func (t *Timers) stop() {
+	for _, tm := range t.timers {
+		tm.Stop()
+	}
+	for _, a := range t.animations {
+		a.Stop()
+	}
+	t.timers = nil
+	t.animations = nil
+}
+
+func (t *Timers) addTimer(tm *Timer) *Timer {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if !tm.Active() {
+		return tm
+	}
+
+	// Сработавшие и остановленные таймеры больше не нужны
+	timers := t.timers[:0]
+	for _, r := range t.timers {
+		if r.Active() {
+			timers = append(timers, r)
+		}
+	}
+	t.timers = append(timers, tm)
+
+	if t.clock != nil {
+		t.clock.addTimer(tm)
+	}
+	return tm
+}
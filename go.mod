@@ -2,7 +2,12 @@ module github.com/anatolypaw/sgui
 
 go 1.22.0
 
-require github.com/srwiley/rasterx v0.0.0-20220730225603-2ab79fcdd4ef
+require (
+	github.com/srwiley/oksvg v0.0.0-20221011165216-be6e8873101c
+	github.com/srwiley/rasterx v0.0.0-20220730225603-2ab79fcdd4ef
+)
+
+require golang.org/x/net v0.21.0 // indirect
 
 require (
 	golang.org/x/image v0.15.0
@@ -21,6 +21,9 @@ func NewOverlay(size image.Rectangle) Overlay {
 
 // Добавляет объект (widget) на экран
 func (ui *Overlay) AddWidget(x int, y int, w IWidget) {
+	ui.mu.Lock()
+	defer ui.mu.Unlock()
+
 	obj := Object{
 		Widget:   w,
 		Position: image.Point{X: x, Y: y},
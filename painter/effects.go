@@ -0,0 +1,266 @@
+package painter
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+	"math"
+
+	"github.com/srwiley/rasterx"
+)
+
+// Эффекты объема для фигур: градиентная заливка, тени и фаска
+type Effects struct {
+	// Если задан, то фигура заливается градиентом вместо FillColor.
+	// Координаты градиента отсчитываются от границ фигуры
+	FillGradient Gradient
+
+	Shadow      Shadow // Тень под фигурой
+	InnerShadow Shadow // Тень внутри фигуры, вид нажатой кнопки
+	Bevel       Bevel  // Фаска, вид выпуклой кнопки
+}
+
+// Тень. Если Color nil, то тень не рисуется.
+// Тень под фигурой занимает место внутри изображения,
+// поэтому фигура уменьшается на размытие и смещение тени
+type Shadow struct {
+	Color  color.Color
+	Offset image.Point // Смещение тени, для внутренней тени (2, 2) затемняет левый верхний край
+	Blur   float64     // Радиус размытия
+}
+
+// Фаска: светлый левый верхний и темный правый нижний внутренний край.
+// Если Width 0, то фаска не рисуется
+type Bevel struct {
+	Width float64
+	Light color.Color
+	Dark  color.Color
+}
+
+// Радиусы скругления отдельных углов
+type CornerRadii struct {
+	TopLeft, TopRight, BottomRight, BottomLeft float64
+}
+
+// Возвращает true, если ни один радиус не задан
+func (r CornerRadii) zero() bool {
+	return r == CornerRadii{}
+}
+
+// Отступы от краев изображения, нужные тени под фигурой
+func (s Shadow) margins() (left, top, right, bottom int) {
+	if s.Color == nil {
+		return 0, 0, 0, 0
+	}
+	spread := int(math.Ceil(s.Blur))
+	return max(0, spread-s.Offset.X), max(0, spread-s.Offset.Y),
+		max(0, spread+s.Offset.X), max(0, spread+s.Offset.Y)
+}
+
+// Рисует фигуру path с эффектами: тень, заливку, внутреннюю тень и фаску.
+// bounds - границы фигуры для градиента
+func drawShape(img *image.RGBA, path *rasterx.Path, bounds image.Rectangle, fill color.Color, e Effects) {
+	size := img.Rect.Size()
+
+	var mask *image.Alpha
+	if e.Shadow.Color != nil || e.InnerShadow.Color != nil || e.Bevel.Width > 0 {
+		mask = shapeMask(size, path)
+	}
+
+	if e.Shadow.Color != nil {
+		shadow := shiftAlpha(mask, e.Shadow.Offset, 0)
+		blurAlpha(shadow, e.Shadow.Blur)
+		draw.DrawMask(img, img.Rect, image.NewUniform(e.Shadow.Color), image.Point{}, shadow, image.Point{}, draw.Over)
+	}
+
+	scanner := rasterx.NewScannerGV(size.X, size.Y, img, img.Bounds())
+	filler := rasterx.NewFiller(size.X, size.Y, scanner)
+	switch {
+	case e.FillGradient != nil:
+		filler.SetColor(gradientFunc(e.FillGradient, bounds))
+	case fill != nil:
+		filler.SetColor(fill)
+	default:
+		filler = nil
+	}
+	if filler != nil {
+		path.AddTo(filler)
+		filler.Draw()
+	}
+
+	if e.InnerShadow.Color != nil {
+		drawInnerShadow(img, mask, e.InnerShadow)
+	}
+
+	if e.Bevel.Width > 0 {
+		w := int(math.Ceil(e.Bevel.Width))
+		if e.Bevel.Light != nil {
+			drawInnerShadow(img, mask, Shadow{Color: e.Bevel.Light, Offset: image.Point{w, w}, Blur: e.Bevel.Width / 2})
+		}
+		if e.Bevel.Dark != nil {
+			drawInnerShadow(img, mask, Shadow{Color: e.Bevel.Dark, Offset: image.Point{-w, -w}, Blur: e.Bevel.Width / 2})
+		}
+	}
+}
+
+// Рисует тень внутри фигуры с маской mask:
+// смещенное и размытое дополнение фигуры, обрезанное по фигуре
+func drawInnerShadow(img *image.RGBA, mask *image.Alpha, s Shadow) {
+	inv := image.NewAlpha(mask.Rect)
+	for i, a := range mask.Pix {
+		inv.Pix[i] = 255 - a
+	}
+	shadow := shiftAlpha(inv, s.Offset, 255)
+	blurAlpha(shadow, s.Blur)
+
+	for i, a := range mask.Pix {
+		shadow.Pix[i] = uint8(uint16(shadow.Pix[i]) * uint16(a) / 255)
+	}
+	draw.DrawMask(img, img.Rect, image.NewUniform(s.Color), image.Point{}, shadow, image.Point{}, draw.Over)
+}
+
+// Растеризует фигуру в маску
+func shapeMask(size image.Point, path *rasterx.Path) *image.Alpha {
+	mask := image.NewAlpha(image.Rectangle{Max: size})
+	scanner := rasterx.NewScannerGV(size.X, size.Y, mask, mask.Bounds())
+	filler := rasterx.NewFiller(size.X, size.Y, scanner)
+	filler.SetColor(color.White)
+	path.AddTo(filler)
+	filler.Draw()
+	return mask
+}
+
+// Возвращает маску, смещенную на offset.
+// Открывшиеся края заполняются значением edge
+func shiftAlpha(mask *image.Alpha, offset image.Point, edge uint8) *image.Alpha {
+	out := image.NewAlpha(mask.Rect)
+	w, h := mask.Rect.Dx(), mask.Rect.Dy()
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			sx, sy := x-offset.X, y-offset.Y
+			a := edge
+			if sx >= 0 && sx < w && sy >= 0 && sy < h {
+				a = mask.Pix[sy*mask.Stride+sx]
+			}
+			out.Pix[y*out.Stride+x] = a
+		}
+	}
+	return out
+}
+
+// Размывает маску на радиус radius.
+// Три прохода box blur по каждой оси приближают размытие по Гауссу
+func blurAlpha(mask *image.Alpha, radius float64) {
+	r := int(math.Round(radius / 3))
+	if r < 1 {
+		return
+	}
+	w, h := mask.Rect.Dx(), mask.Rect.Dy()
+	line := make([]uint8, max(w, h))
+	for pass := 0; pass < 3; pass++ {
+		for y := 0; y < h; y++ {
+			boxBlur(mask.Pix[y*mask.Stride:], 1, w, r, line)
+		}
+		for x := 0; x < w; x++ {
+			boxBlur(mask.Pix[x:], mask.Stride, h, r, line)
+		}
+	}
+}
+
+// Усредняет n значений pix с шагом stride по окну радиуса r.
+// За краями продолжается крайнее значение
+func boxBlur(pix []uint8, stride, n, r int, line []uint8) {
+	for i := 0; i < n; i++ {
+		line[i] = pix[i*stride]
+	}
+	at := func(i int) int {
+		return int(line[min(max(i, 0), n-1)])
+	}
+
+	sum := 0
+	for i := -r; i <= r; i++ {
+		sum += at(i)
+	}
+	window := 2*r + 1
+	for i := 0; i < n; i++ {
+		pix[i*stride] = uint8(sum / window)
+		sum += at(i+r+1) - at(i-r)
+	}
+}
+
+// Функция цвета градиента для растеризатора
+func gradientFunc(g Gradient, bounds image.Rectangle) rasterx.ColorFunc {
+	stops := sortedStops(g.stops())
+	size := bounds.Size()
+	return func(x, y int) color.Color {
+		if len(stops) == 0 {
+			return color.Transparent
+		}
+		t := g.offset(float64(x-bounds.Min.X)+0.5, float64(y-bounds.Min.Y)+0.5, size)
+		return gradientColor(stops, t)
+	}
+}
+
+// Ограничивает радиусы половиной меньшей стороны прямоугольника w на h
+func (r CornerRadii) clamp(w, h float64) CornerRadii {
+	limit := math.Max(0, math.Min(w, h)/2)
+	c := func(v float64) float64 { return math.Max(0, math.Min(v, limit)) }
+	return CornerRadii{c(r.TopLeft), c(r.TopRight), c(r.BottomRight), c(r.BottomLeft)}
+}
+
+// Радиусы контура, смещенного наружу на d (внутрь, если d < 0).
+// Острые углы остаются острыми
+func (r CornerRadii) offset(d float64) CornerRadii {
+	o := func(v float64) float64 {
+		if v == 0 {
+			return 0
+		}
+		return math.Max(0, v+d)
+	}
+	return CornerRadii{o(r.TopLeft), o(r.TopRight), o(r.BottomRight), o(r.BottomLeft)}
+}
+
+// Контур скругленного прямоугольника x0, y0, x1, y1 с радиусами углов radii
+func roundRectPath(x0, y0, x1, y1 float64, radii CornerRadii) *rasterx.Path {
+	var path rasterx.Path
+	addRoundRect(&path, x0, y0, x1, y1, radii)
+	return &path
+}
+
+// Добавляет контур скругленного прямоугольника в path
+func addRoundRect(path *rasterx.Path, x0, y0, x1, y1 float64, radii CornerRadii) {
+	r := radii.clamp(x1-x0, y1-y0)
+	tl, tr, br, bl := r.TopLeft, r.TopRight, r.BottomRight, r.BottomLeft
+
+	// Одинаковые углы рисуются средствами rasterx
+	if tl == tr && tr == br && br == bl {
+		if tl == 0 {
+			rasterx.AddRect(x0, y0, x1, y1, 0, path)
+		} else {
+			rasterx.AddRoundRect(x0, y0, x1, y1, tl, tl, 0, rasterx.RoundGap, path)
+		}
+		return
+	}
+
+	var p Path
+	p.MoveTo(x0+tl, y0)
+	p.LineTo(x1-tr, y0)
+	if tr > 0 {
+		p.Arc(x1-tr, y0+tr, tr, -90, 90)
+	}
+	p.LineTo(x1, y1-br)
+	if br > 0 {
+		p.Arc(x1-br, y1-br, br, 0, 90)
+	}
+	p.LineTo(x0+bl, y1)
+	if bl > 0 {
+		p.Arc(x0+bl, y1-bl, bl, 90, 90)
+	}
+	p.LineTo(x0, y0+tl)
+	if tl > 0 {
+		p.Arc(x0+tl, y0+tl, tl, 180, 90)
+	}
+	p.Close()
+
+	*path = append(*path, p.path...)
+}
@@ -0,0 +1,42 @@
+package painter
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+)
+
+// Возвращает серую копию изображения со сниженным контрастом.
+// fade 0..1 - насколько цвета приближаются к среднему серому.
+// Используется для отображения отключенных виджетов
+func Grayscale(img *image.RGBA, fade float64) *image.RGBA {
+	out := image.NewRGBA(img.Bounds())
+	b := img.Bounds()
+
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			c := img.RGBAAt(x, y)
+			if c.A == 0 {
+				continue
+			}
+
+			// Яркость. Цвета premultiplied, поэтому серый тоже
+			lum := 0.299*float64(c.R) + 0.587*float64(c.G) + 0.114*float64(c.B)
+			mid := 128 * float64(c.A) / 255
+			v := uint8(lum + (mid-lum)*fade)
+
+			out.SetRGBA(x, y, color.RGBA{v, v, v, c.A})
+		}
+	}
+
+	return out
+}
+
+// Возвращает изображение цвета c с прозрачностью исходного изображения.
+// Используется для окраски монохромных иконок в цвет текста
+func Tint(img image.Image, c color.Color) *image.RGBA {
+	b := img.Bounds()
+	out := image.NewRGBA(image.Rectangle{Max: b.Size()})
+	draw.DrawMask(out, out.Rect, image.NewUniform(c), image.Point{}, img, b.Min, draw.Src)
+	return out
+}
@@ -0,0 +1,146 @@
+package painter
+
+import (
+	"image"
+	"image/color"
+	"math"
+	"sort"
+)
+
+// Градиентная заливка.
+// Координаты задаются в долях размера заливаемой области:
+// (0, 0) - левый верхний угол, (1, 1) - правый нижний
+type Gradient interface {
+	// Возвращает положение точки (x, y) области размера size на градиенте, 0..1
+	offset(x, y float64, size image.Point) float64
+	stops() []GradientStop
+}
+
+// Опорный цвет градиента
+type GradientStop struct {
+	Offset float64 // Положение на градиенте, 0..1
+	Color  color.Color
+}
+
+// Линейный градиент от точки (X1, Y1) к точке (X2, Y2)
+type LinearGradient struct {
+	X1, Y1 float64
+	X2, Y2 float64
+	Stops  []GradientStop
+}
+
+// Радиальный градиент от центра (CX, CY) до радиуса R.
+// R задается в долях большей стороны области
+type RadialGradient struct {
+	CX, CY float64
+	R      float64
+	Stops  []GradientStop
+}
+
+// Вертикальный градиент из двух цветов, сверху вниз
+func VerticalGradient(top, bottom color.Color) LinearGradient {
+	return LinearGradient{
+		X1: 0, Y1: 0, X2: 0, Y2: 1,
+		Stops: []GradientStop{{0, top}, {1, bottom}},
+	}
+}
+
+func (g LinearGradient) offset(x, y float64, size image.Point) float64 {
+	x1, y1 := g.X1*float64(size.X), g.Y1*float64(size.Y)
+	dx, dy := (g.X2-g.X1)*float64(size.X), (g.Y2-g.Y1)*float64(size.Y)
+	l := dx*dx + dy*dy
+	if l == 0 {
+		return 0
+	}
+	// Проекция точки на вектор градиента
+	return ((x-x1)*dx + (y-y1)*dy) / l
+}
+
+func (g LinearGradient) stops() []GradientStop {
+	return g.Stops
+}
+
+func (g RadialGradient) offset(x, y float64, size image.Point) float64 {
+	r := g.R * float64(max(size.X, size.Y))
+	if r <= 0 {
+		return 1
+	}
+	dx, dy := x-g.CX*float64(size.X), y-g.CY*float64(size.Y)
+	return math.Hypot(dx, dy) / r
+}
+
+func (g RadialGradient) stops() []GradientStop {
+	return g.Stops
+}
+
+// Рисует область размера size, залитую градиентом
+func DrawGradient(size image.Point, g Gradient) *image.RGBA {
+	img := image.NewRGBA(image.Rectangle{Max: size})
+	fillGradient(img, img.Bounds(), g)
+	return img
+}
+
+// Заливает область r изображения градиентом.
+// Координаты градиента отсчитываются от r
+func fillGradient(img *image.RGBA, r image.Rectangle, g Gradient) {
+	stops := sortedStops(g.stops())
+	if len(stops) == 0 {
+		return
+	}
+
+	size := r.Size()
+	for y := r.Min.Y; y < r.Max.Y; y++ {
+		for x := r.Min.X; x < r.Max.X; x++ {
+			// Цвет берется в центре пикселя
+			t := g.offset(float64(x-r.Min.X)+0.5, float64(y-r.Min.Y)+0.5, size)
+			img.SetRGBA(x, y, gradientColor(stops, t))
+		}
+	}
+}
+
+// Возвращает цвет градиента в точке t
+func gradientColor(stops []GradientStop, t float64) color.RGBA {
+	if t <= stops[0].Offset {
+		return toRGBA(stops[0].Color)
+	}
+	last := stops[len(stops)-1]
+	if t >= last.Offset {
+		return toRGBA(last.Color)
+	}
+
+	for i := 1; i < len(stops); i++ {
+		if t > stops[i].Offset {
+			continue
+		}
+		a, b := stops[i-1], stops[i]
+		k := (t - a.Offset) / (b.Offset - a.Offset)
+		return lerpRGBA(toRGBA(a.Color), toRGBA(b.Color), k)
+	}
+	return toRGBA(last.Color)
+}
+
+func sortedStops(stops []GradientStop) []GradientStop {
+	s := append([]GradientStop(nil), stops...)
+	sort.SliceStable(s, func(i, j int) bool { return s[i].Offset < s[j].Offset })
+	return s
+}
+
+// Преобразует цвет в RGBA (с premultiplied alpha). nil - прозрачный
+func toRGBA(c color.Color) color.RGBA {
+	if c == nil {
+		return color.RGBA{}
+	}
+	return color.RGBAModel.Convert(c).(color.RGBA)
+}
+
+func lerpRGBA(a, b color.RGBA, k float64) color.RGBA {
+	lerp := func(x, y uint8) uint8 {
+		return uint8(math.Round(float64(x) + (float64(y)-float64(x))*k))
+	}
+	return color.RGBA{
+		R: lerp(a.R, b.R),
+		G: lerp(a.G, b.G),
+		B: lerp(a.B, b.B),
+		A: lerp(a.A, b.A),
+	}
+}
@@ -0,0 +1,97 @@
+package painter
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/draw"
+	_ "image/gif"
+	_ "image/jpeg"
+	_ "image/png"
+
+	xdraw "golang.org/x/image/draw"
+)
+
+// Способ вписывания изображения в заданный размер
+type ScaleMode int
+
+const (
+	ScaleStretch ScaleMode = iota // Растянуть без сохранения пропорций
+	ScaleFit                      // Вписать целиком с сохранением пропорций
+	ScaleTile                     // Замостить без масштабирования
+	ScaleCenter                   // Разместить в центре без масштабирования
+	ScaleFill                     // Заполнить целиком с сохранением пропорций, обрезав края
+)
+
+// Декодирует изображение PNG, JPEG или GIF (первый кадр)
+func DecodeImage(data []byte) (image.Image, error) {
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	return img, nil
+}
+
+// Вписывает изображение src в размер size способом mode.
+// Области, не закрытые изображением, заливаются цветом back
+// (если nil, то остаются прозрачными).
+// Если размер не положительный, то возвращается пустое изображение
+func ScaleImage(src image.Image, size image.Point, mode ScaleMode, back color.Color) *image.RGBA {
+	if size.X <= 0 || size.Y <= 0 {
+		return image.NewRGBA(image.Rectangle{})
+	}
+
+	img := image.NewRGBA(image.Rectangle{Max: size})
+	if back != nil {
+		draw.Draw(img, img.Bounds(), image.NewUniform(back), image.Point{}, draw.Src)
+	}
+
+	sb := src.Bounds()
+	if sb.Empty() {
+		return img
+	}
+
+	switch mode {
+	case ScaleStretch:
+		xdraw.CatmullRom.Scale(img, img.Bounds(), src, sb, draw.Over, nil)
+
+	case ScaleFit:
+		// Масштаб по стороне, которая упирается раньше
+		w, h := size.X, sb.Dy()*size.X/sb.Dx()
+		if h > size.Y {
+			w, h = sb.Dx()*size.Y/sb.Dy(), size.Y
+		}
+		dst := centered(image.Point{max(w, 1), max(h, 1)}, size)
+		xdraw.CatmullRom.Scale(img, dst, src, sb, draw.Over, nil)
+
+	case ScaleFill:
+		// Масштаб по стороне, которая упирается позже.
+		// Выступающие за размер края обрезаются
+		w, h := size.X, sb.Dy()*size.X/sb.Dx()
+		if h < size.Y {
+			w, h = sb.Dx()*size.Y/sb.Dy(), size.Y
+		}
+		dst := centered(image.Point{w, h}, size)
+		xdraw.CatmullRom.Scale(img, dst, src, sb, draw.Over, nil)
+
+	case ScaleTile:
+		for y := 0; y < size.Y; y += sb.Dy() {
+			for x := 0; x < size.X; x += sb.Dx() {
+				dst := image.Rect(x, y, x+sb.Dx(), y+sb.Dy())
+				draw.Draw(img, dst, src, sb.Min, draw.Over)
+			}
+		}
+
+	case ScaleCenter:
+		dst := centered(sb.Size(), size)
+		draw.Draw(img, dst, src, sb.Min, draw.Over)
+	}
+
+	return img
+}
+
+// Возвращает прямоугольник размера inner в центре области размера outer
+func centered(inner, outer image.Point) image.Rectangle {
+	pos := outer.Sub(inner).Div(2)
+	return image.Rectangle{Min: pos, Max: pos.Add(inner)}
+}
@@ -1,16 +1,40 @@
 package painter
 
 import (
+	"fmt"
 	"image"
 	"image/color"
+	"image/draw"
+	"math"
 
+	"github.com/anatolypaw/sgui/rendercache"
 	"github.com/srwiley/rasterx"
 	"golang.org/x/image/font"
 	"golang.org/x/image/font/basicfont"
 	"golang.org/x/image/math/fixed"
 )
 
-const quarterCircleControl = 1 - 0.55228
+// Положение обводки относительно края фигуры.
+// Размер изображения не меняется: при обводке по центру и снаружи
+// фигура уменьшается, чтобы обводка поместилась в изображение
+type StrokeAlign int
+
+const (
+	StrokeInside  StrokeAlign = iota // Обводка внутри фигуры
+	StrokeCenter                     // Середина обводки на краю фигуры
+	StrokeOutside                    // Обводка снаружи фигуры
+)
+
+// Расстояние от края фигуры до внешнего края обводки ширины width
+func (a StrokeAlign) outset(width float64) float64 {
+	switch a {
+	case StrokeCenter:
+		return width / 2
+	case StrokeOutside:
+		return width
+	}
+	return 0
+}
 
 type Circle struct {
 	Radius      int
@@ -18,6 +42,9 @@ type Circle struct {
 	BackColor   color.Color
 	StrokeWidth float64
 	StrokeColor color.Color
+	StrokeAlign StrokeAlign
+
+	Effects
 }
 
 type Rectangle struct {
@@ -27,17 +54,146 @@ type Rectangle struct {
 	CornerRadius float64
 	StrokeWidth  float64
 	StrokeColor  color.Color
+	StrokeAlign  StrokeAlign
+
+	// Радиусы отдельных углов.
+	// Если задан хотя бы один, то CornerRadius не используется
+	Radii CornerRadii
+
+	Effects
 }
 
-// Круг с обводкой
+// Круг с обводкой.
+// Рендеры кэшируются по параметрам круга, возвращается копия
 func DrawCircle(c Circle) *image.RGBA {
+	return rendercache.GetCopy(c.key(), func() *image.RGBA {
+		return drawCircle(c)
+	})
+}
+
+// Цвет в ключе кэша. Приводится к color.RGBA, чтобы одинаковые цвета
+// разных типов давали один ключ. set отличает nil от прозрачного цвета
+type colorKey struct {
+	c   color.RGBA
+	set bool
+}
+
+func keyColor(c color.Color) colorKey {
+	if c == nil {
+		return colorKey{}
+	}
+	return colorKey{color.RGBAModel.Convert(c).(color.RGBA), true}
+}
+
+type shadowKey struct {
+	color  colorKey
+	offset image.Point
+	blur   float64
+}
+
+func keyShadow(s Shadow) shadowKey {
+	return shadowKey{keyColor(s.Color), s.Offset, s.Blur}
+}
+
+type effectsKey struct {
+	gradient    string // Текстовое представление градиента, обычно пустое
+	shadow      shadowKey
+	innerShadow shadowKey
+	bevelWidth  float64
+	bevelLight  colorKey
+	bevelDark   colorKey
+}
+
+func keyEffects(e Effects) effectsKey {
+	return effectsKey{
+		gradient:    gradientKey(e.FillGradient),
+		shadow:      keyShadow(e.Shadow),
+		innerShadow: keyShadow(e.InnerShadow),
+		bevelWidth:  e.Bevel.Width,
+		bevelLight:  keyColor(e.Bevel.Light),
+		bevelDark:   keyColor(e.Bevel.Dark),
+	}
+}
+
+// Градиенты содержат срезы опорных цветов, поэтому в ключ входит
+// их текстовое представление с цветами, приведенными к color.RGBA
+func gradientKey(g Gradient) string {
+	stops := func(s []GradientStop) []GradientStop {
+		out := make([]GradientStop, len(s))
+		for i, st := range s {
+			out[i] = GradientStop{st.Offset, keyColor(st.Color).c}
+		}
+		return out
+	}
+
+	switch g := g.(type) {
+	case nil:
+		return ""
+	case LinearGradient:
+		g.Stops = stops(g.Stops)
+		return fmt.Sprintf("%#v", g)
+	case RadialGradient:
+		g.Stops = stops(g.Stops)
+		return fmt.Sprintf("%#v", g)
+	}
+	return fmt.Sprintf("%#v", g)
+}
+
+// Ключ круга в кэше рендеров
+type circleKey struct {
+	radius      int
+	fill        colorKey
+	back        colorKey
+	strokeWidth float64
+	stroke      colorKey
+	align       StrokeAlign
+	effects     effectsKey
+}
+
+func (c Circle) key() circleKey {
+	return circleKey{
+		radius:      c.Radius,
+		fill:        keyColor(c.FillColor),
+		back:        keyColor(c.BackColor),
+		strokeWidth: c.StrokeWidth,
+		stroke:      keyColor(c.StrokeColor),
+		align:       c.StrokeAlign,
+		effects:     keyEffects(c.Effects),
+	}
+}
+
+// Ключ прямоугольника в кэше рендеров
+type rectangleKey struct {
+	size         image.Point
+	fill         colorKey
+	back         colorKey
+	cornerRadius float64
+	strokeWidth  float64
+	stroke       colorKey
+	align        StrokeAlign
+	radii        CornerRadii
+	effects      effectsKey
+}
+
+func (r Rectangle) key() rectangleKey {
+	return rectangleKey{
+		size:         r.Size,
+		fill:         keyColor(r.FillColor),
+		back:         keyColor(r.BackColor),
+		cornerRadius: r.CornerRadius,
+		strokeWidth:  r.StrokeWidth,
+		stroke:       keyColor(r.StrokeColor),
+		align:        r.StrokeAlign,
+		radii:        r.Radii,
+		effects:      keyEffects(r.Effects),
+	}
+}
+
+func drawCircle(c Circle) *image.RGBA {
 	size := c.Radius * 2
 	rect := image.Rect(0, 0, size, size)
 	img := image.NewRGBA(rect)
 
-	mid := float64(c.Radius)
-	scanner := rasterx.NewScannerGV(size, size, img, img.Bounds())
-
 	if c.BackColor != nil {
 		// Заполняем фон
 		for x := 0; x < img.Rect.Dx(); x++ {
@@ -47,34 +203,80 @@ func DrawCircle(c Circle) *image.RGBA {
 		}
 	}
 
-	if c.FillColor != nil {
+	// Круг уменьшается, чтобы тень и обводка поместились в изображение
+	l, t, r, b := c.Shadow.margins()
+	sw := strokeWidth(c.StrokeColor, c.StrokeWidth)
+	out := c.StrokeAlign.outset(sw)
+	mid := float64(c.Radius)
+	radius := max(0, float64(c.Radius-max(l, t, r, b))-out)
+	bounds := image.Rect(int(mid-radius), int(mid-radius), int(math.Ceil(mid+radius)), int(math.Ceil(mid+radius)))
+
+	if c.FillColor != nil || c.FillGradient != nil {
 		// Рисуем основу
-		filler := rasterx.NewFiller(size, size, scanner)
-		filler.SetColor(c.FillColor)
-		rasterx.AddCircle(mid, mid, float64(c.Radius), filler)
-		filler.Draw()
+		var path rasterx.Path
+		rasterx.AddCircle(mid, mid, radius, &path)
+		drawShape(img, &path, bounds, c.FillColor, c.Effects)
 	}
 
-	if c.StrokeColor != nil && c.StrokeWidth > 0 {
-		// Рисуем обводку
-		dasher := rasterx.NewDasher(size, size, scanner)
-		dasher.SetColor(c.StrokeColor)
-		dasher.SetStroke(fixed.Int26_6(c.StrokeWidth*64), 0, nil, nil, nil, 0, nil, 0)
-		rasterx.AddCircle(mid, mid, float64(c.Radius)-c.StrokeWidth/2, dasher)
-		dasher.Draw()
-
+	if sw > 0 {
+		// Рисуем обводку кольцом между внешним и внутренним краем
+		var outer, inner rasterx.Path
+		r := radius + out
+		rasterx.AddCircle(mid, mid, r, &outer)
+		hole := &inner
+		if r-sw > 0 {
+			rasterx.AddCircle(mid, mid, r-sw, hole)
+		} else {
+			hole = nil
+		}
+		fillRing(img, &outer, hole, c.StrokeColor)
 	}
 
 	return img
 }
 
-// Скругленный рямоугольник с обводкой
+// Ширина обводки, 0 - обводка не рисуется
+func strokeWidth(c color.Color, width float64) float64 {
+	if c == nil {
+		return 0
+	}
+	return max(0, width)
+}
+
+// Заливает кольцо между контурами outer и inner цветом c.
+// Внутренний контур может быть nil, тогда заливается весь внешний
+func fillRing(img *image.RGBA, outer, inner *rasterx.Path, c color.Color) {
+	size := img.Rect.Size()
+	mask := shapeMask(size, outer)
+	if inner != nil {
+		hole := shapeMask(size, inner)
+		for i, a := range hole.Pix {
+			mask.Pix[i] -= min(a, mask.Pix[i])
+		}
+	}
+	draw.DrawMask(img, img.Rect, image.NewUniform(c), image.Point{}, mask, image.Point{}, draw.Over)
+}
+
+// Радиусы углов прямоугольника
+func (r Rectangle) radii() CornerRadii {
+	if !r.Radii.zero() {
+		return r.Radii
+	}
+	return CornerRadii{r.CornerRadius, r.CornerRadius, r.CornerRadius, r.CornerRadius}
+}
+
+// Скругленный прямоугольник с обводкой.
+// Рендеры кэшируются по параметрам прямоугольника, возвращается копия
 func DrawRectangle(r Rectangle) *image.RGBA {
+	return rendercache.GetCopy(r.key(), func() *image.RGBA {
+		return drawRectangle(r)
+	})
+}
+
+func drawRectangle(r Rectangle) *image.RGBA {
 	rect := image.Rect(0, 0, r.Size.X, r.Size.Y)
 	img := image.NewRGBA(rect)
 
-	scanner := rasterx.NewScannerGV(r.Size.X, r.Size.Y, img, img.Bounds())
-
 	if r.BackColor != nil {
 		// Заполняем фон
 		for x := 0; x < img.Rect.Dx(); x++ {
@@ -84,72 +286,40 @@ func DrawRectangle(r Rectangle) *image.RGBA {
 		}
 	}
 
-	if r.FillColor != nil {
+	// Прямоугольник уменьшается, чтобы тень и обводка поместились в изображение
+	ml, mt, mr, mb := r.Shadow.margins()
+	sw := strokeWidth(r.StrokeColor, r.StrokeWidth)
+	out := r.StrokeAlign.outset(sw)
+	x0, y0 := float64(ml)+out, float64(mt)+out
+	x1, y1 := float64(r.Size.X-mr)-out, float64(r.Size.Y-mb)-out
+	radii := r.radii().clamp(x1-x0, y1-y0)
+	bounds := image.Rect(int(x0), int(y0), int(math.Ceil(x1)), int(math.Ceil(y1)))
+
+	if (r.FillColor != nil || r.FillGradient != nil) && x1 > x0 && y1 > y0 {
 		// Рисуем основу
-		filler := rasterx.NewFiller(r.Size.X, r.Size.Y, scanner)
-		filler.SetColor(r.FillColor)
-		if r.CornerRadius <= 0 {
-			rasterx.AddRect(0, 0, float64(r.Size.X), float64(r.Size.Y), 0, filler)
-		} else {
-			rasterx.AddRoundRect(0, 0, float64(r.Size.X), float64(r.Size.Y),
-				r.CornerRadius, r.CornerRadius,
-				0, rasterx.RoundGap, filler)
-		}
-		filler.Draw()
-	}
-
-	// BUG: рисует неправильно, если ширина обводки больше радиуса
-	// Рисуем обводку
-	if r.StrokeColor != nil && r.StrokeWidth > 0 {
-		stk := float64(r.StrokeWidth / 2.1)
-		p1x, p1y := stk, stk
-		p2x, p2y := float64(r.Size.X)-stk, stk
-		p3x, p3y := float64(r.Size.X)-stk, float64(r.Size.Y)-stk
-		p4x, p4y := stk, float64(r.Size.Y)-stk
-		rad := float64(r.CornerRadius+2.1) - r.StrokeWidth
-
-		c := quarterCircleControl * rad
-		dasher := rasterx.NewDasher(r.Size.X, r.Size.Y, scanner)
-		dasher.SetColor(r.StrokeColor)
-		dasher.SetStroke(fixed.Int26_6(r.StrokeWidth*64), 0, nil, nil, nil, 0, nil, 0)
-		if c > 0 {
-			dasher.Start(rasterx.ToFixedP(p1x, p1y+rad))
-			dasher.CubeBezier(rasterx.ToFixedP(p1x, p1y+c),
-				rasterx.ToFixedP(p1x+c, p1y),
-				rasterx.ToFixedP(p1x+rad, p2y))
-		} else {
-			dasher.Start(rasterx.ToFixedP(p1x, p1y))
-		}
-		dasher.Line(rasterx.ToFixedP(p2x-rad, p2y))
-		if c > 0 {
-			dasher.CubeBezier(rasterx.ToFixedP(p2x-c, p2y),
-				rasterx.ToFixedP(p2x, p2y+c),
-				rasterx.ToFixedP(p2x, p2y+rad))
-		}
-		dasher.Line(rasterx.ToFixedP(p3x, p3y-rad))
-		if c > 0 {
-			dasher.CubeBezier(rasterx.ToFixedP(p3x, p3y-c),
-				rasterx.ToFixedP(p3x-c, p3y),
-				rasterx.ToFixedP(p3x-rad, p3y))
-		}
-		dasher.Line(rasterx.ToFixedP(p4x+rad, p4y))
-		if c > 0 {
-			dasher.CubeBezier(rasterx.ToFixedP(p4x+c, p4y),
-				rasterx.ToFixedP(p4x, p4y-c),
-				rasterx.ToFixedP(p4x, p4y-rad))
+		drawShape(img, roundRectPath(x0, y0, x1, y1, radii), bounds, r.FillColor, r.Effects)
+	}
+
+	if sw > 0 {
+		// Рисуем обводку кольцом между внешним и внутренним контуром.
+		// Радиусы контуров смещаются вместе с краями, поэтому ширина
+		// обводки одинакова на сторонах и углах при любом радиусе
+		outer := radii.offset(out)
+		ox0, oy0, ox1, oy1 := x0-out, y0-out, x1+out, y1+out
+		var hole *rasterx.Path
+		if ix0, iy0, ix1, iy1 := ox0+sw, oy0+sw, ox1-sw, oy1-sw; ix1 > ix0 && iy1 > iy0 {
+			hole = roundRectPath(ix0, iy0, ix1, iy1, outer.offset(-sw))
 		}
-		dasher.Stop(true)
-		dasher.Draw()
+		fillRing(img, roundRectPath(ox0, oy0, ox1, oy1, outer), hole, r.StrokeColor)
 	}
 
 	return img
-
 }
 
 // Добавляет текст на изображение
 func AddLabel(img *image.RGBA, x, y int, label string) {
 	col := color.RGBA{0, 0, 0, 255}
-	point := fixed.Point26_6{fixed.I(x), fixed.I(y)}
+	point := fixed.Point26_6{X: fixed.I(x), Y: fixed.I(y)}
 
 	d := &font.Drawer{
 		Dst:  img,
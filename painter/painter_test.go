@@ -1,20 +1,22 @@
-package painter
+package painter_test
 
 import (
-	"fmt"
 	"image"
 	"image/color"
-	"image/png"
-	"os"
+	"image/draw"
 	"testing"
+
+	"github.com/anatolypaw/sgui/painter"
+	"github.com/anatolypaw/sgui/rendercache"
+	"github.com/anatolypaw/sgui/sguitest"
 )
 
 func TestDrawCircle(t *testing.T) {
 	tests := []struct {
 		name string
-		c    Circle
+		c    painter.Circle
 	}{
-		{"red", Circle{
+		{"circle_red", painter.Circle{
 			Radius:      50,
 			FillColor:   color.RGBA{0, 255, 0, 255},
 			StrokeWidth: 3,
@@ -23,21 +25,8 @@ func TestDrawCircle(t *testing.T) {
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-
-			fname := fmt.Sprintf("./test_result/circle_%s.png", tt.name)
-			f, err := os.Create(fname)
-			if err != nil {
-				return
-			}
-			defer f.Close()
-
-			img := DrawCircle(tt.c)
-
-			err = png.Encode(f, img)
-			if err != nil {
-				return
-			}
-
+			img := painter.DrawCircle(tt.c)
+			sguitest.AssertGolden(t, tt.name, img, 1)
 		})
 	}
 }
@@ -45,9 +34,9 @@ func TestDrawCircle(t *testing.T) {
 func TestDrawRectangle(t *testing.T) {
 	tests := []struct {
 		name string
-		r    Rectangle
+		r    painter.Rectangle
 	}{
-		{"red", Rectangle{
+		{"rectangle_red", painter.Rectangle{
 			Size:         image.Point{20, 50},
 			FillColor:    color.RGBA{94, 94, 94, 255},
 			CornerRadius: 0,
@@ -57,21 +46,365 @@ func TestDrawRectangle(t *testing.T) {
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
+			img := painter.DrawRectangle(tt.r)
+			sguitest.AssertGolden(t, tt.name, img, 1)
+		})
+	}
+}
+
+func TestScaleImage(t *testing.T) {
+	src := image.NewRGBA(image.Rect(0, 0, 10, 5))
+	draw.Draw(src, src.Bounds(), image.NewUniform(color.RGBA{255, 0, 0, 255}), image.Point{}, draw.Src)
+	back := color.RGBA{0, 0, 255, 255}
 
-			fname := fmt.Sprintf("./test_result/rectangle_%s.png", tt.name)
-			f, err := os.Create(fname)
-			if err != nil {
-				return
+	tests := []struct {
+		mode      painter.ScaleMode
+		covered   image.Point // точка, закрытая изображением
+		uncovered image.Point // точка, залитая фоном
+	}{
+		{painter.ScaleFit, image.Point{20, 20}, image.Point{20, 2}},
+		{painter.ScaleCenter, image.Point{20, 20}, image.Point{10, 10}},
+		{painter.ScaleTile, image.Point{39, 39}, image.Point{-1, -1}},
+		{painter.ScaleStretch, image.Point{0, 0}, image.Point{-1, -1}},
+		{painter.ScaleFill, image.Point{0, 0}, image.Point{-1, -1}},
+	}
+	for _, tt := range tests {
+		img := painter.ScaleImage(src, image.Point{40, 40}, tt.mode, back)
+		if img.Bounds().Size() != (image.Point{40, 40}) {
+			t.Errorf("mode %d: size %v", tt.mode, img.Bounds().Size())
+		}
+		if got := img.RGBAAt(tt.covered.X, tt.covered.Y); got.R < 250 || got.B > 5 {
+			t.Errorf("mode %d: covered pixel %v", tt.mode, got)
+		}
+		if tt.uncovered.X >= 0 {
+			if got := img.RGBAAt(tt.uncovered.X, tt.uncovered.Y); got != back {
+				t.Errorf("mode %d: uncovered pixel %v", tt.mode, got)
 			}
-			defer f.Close()
+		}
+	}
+
+	if img := painter.ScaleImage(src, image.Point{-1, 10}, painter.ScaleFit, back); !img.Bounds().Empty() {
+		t.Errorf("negative size: %v", img.Bounds())
+	}
+}
+
+func TestDrawGradient(t *testing.T) {
+	img := painter.DrawGradient(image.Point{100, 10},
+		painter.LinearGradient{X2: 1, Stops: []painter.GradientStop{
+			{0, color.Black},
+			{1, color.White},
+		}})
+	if got := img.RGBAAt(0, 5).R; got > 2 {
+		t.Errorf("left edge = %d, want ~0", got)
+	}
+	if got := img.RGBAAt(50, 5).R; got < 125 || got > 130 {
+		t.Errorf("middle = %d, want ~128", got)
+	}
+
+	img = painter.DrawGradient(image.Point{20, 20},
+		painter.RadialGradient{CX: 0.5, CY: 0.5, R: 0.5, Stops: []painter.GradientStop{
+			{0, color.White},
+			{1, color.Black},
+		}})
+	if center, corner := img.RGBAAt(10, 10).R, img.RGBAAt(0, 0).R; center < 230 || corner != 0 {
+		t.Errorf("radial: center %d, corner %d", center, corner)
+	}
+}
+
+func TestDrawSVG(t *testing.T) {
+	svg := []byte(`<svg xmlns="http://www.w3.org/2000/svg" viewBox="0 0 10 10">
+<rect x="0" y="0" width="5" height="10" fill="#ff0000"/>
+</svg>`)
+
+	// Иконка 10x10 вписывается в 40x20 по высоте и размещается в центре
+	img, err := painter.DrawSVG(svg, image.Point{40, 20}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := img.RGBAAt(15, 10); got != (color.RGBA{255, 0, 0, 255}) {
+		t.Errorf("left half of the icon: %v", got)
+	}
+	if got := img.RGBAAt(25, 10); got.A != 0 {
+		t.Errorf("right half of the icon: %v", got)
+	}
+	if got := img.RGBAAt(5, 10); got.A != 0 {
+		t.Errorf("outside the icon: %v", got)
+	}
+
+	green := color.RGBA{0, 255, 0, 255}
+	tinted, err := painter.DrawSVG(svg, image.Point{40, 20}, green)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := tinted.RGBAAt(15, 10); got != green {
+		t.Errorf("color override: %v", got)
+	}
+
+	// Изменение результата не портит кэш
+	draw.Draw(tinted, tinted.Bounds(), image.Transparent, image.Point{}, draw.Src)
+	again, _ := painter.DrawSVG(svg, image.Point{40, 20}, green)
+	if again == tinted || again.RGBAAt(15, 10) != green {
+		t.Errorf("cached render is shared: %v", again.RGBAAt(15, 10))
+	}
+
+	if img, err := painter.DrawSVG(svg, image.Point{-1, 10}, nil); err != nil || !img.Bounds().Empty() {
+		t.Errorf("negative size: %v, %v", img.Bounds(), err)
+	}
+
+	if _, err := painter.DrawSVG([]byte("not svg"), image.Point{10, 10}, nil); err == nil {
+		t.Errorf("no error for invalid data")
+	}
+}
+
+func TestPrimitives(t *testing.T) {
+	red := color.RGBA{255, 0, 0, 255}
+	blue := color.RGBA{0, 0, 255, 255}
+
+	// Изображение не с нулевым началом, как часть экрана
+	dst := image.NewRGBA(image.Rect(100, 100, 200, 200))
+
+	painter.DrawLine(dst, image.Point{100, 100}, 10, 10, 90, 10,
+		painter.Stroke{Color: red, Width: 4})
+	if got := dst.RGBAAt(150, 110); got != red {
+		t.Errorf("line: %v", got)
+	}
+	if got := dst.RGBAAt(150, 115); got.A != 0 {
+		t.Errorf("outside line: %v", got)
+	}
+
+	// Штрих 10, промежуток 10: середина второго промежутка пустая
+	painter.DrawLine(dst, image.Point{100, 100}, 10, 30, 90, 30,
+		painter.Stroke{Color: red, Width: 4, Dash: []float64{10, 10}})
+	if got := dst.RGBAAt(115, 130); got != red {
+		t.Errorf("dash: %v", got)
+	}
+	if got := dst.RGBAAt(125, 130); got.A != 0 {
+		t.Errorf("dash gap: %v", got)
+	}
+
+	// Треугольник со смещением
+	painter.DrawPolygon(dst, image.Point{150, 150},
+		[]painter.Point{{0, 0}, {40, 0}, {0, 40}}, blue, painter.Stroke{})
+	if got := dst.RGBAAt(155, 155); got != blue {
+		t.Errorf("polygon inside: %v", got)
+	}
+	if got := dst.RGBAAt(185, 185); got.A != 0 {
+		t.Errorf("polygon outside: %v", got)
+	}
+
+	// Четверть круга от 0 до 90 градусов - правый нижний сектор
+	pie := image.NewRGBA(image.Rect(0, 0, 40, 40))
+	painter.DrawPie(pie, image.Point{}, 20, 20, 18, 0, 90, blue, painter.Stroke{})
+	if got := pie.RGBAAt(28, 28); got != blue {
+		t.Errorf("pie inside: %v", got)
+	}
+	if got := pie.RGBAAt(12, 12); got.A != 0 {
+		t.Errorf("pie outside: %v", got)
+	}
 
-			img := DrawRectangle(tt.r)
+	ellipse := image.NewRGBA(image.Rect(0, 0, 40, 20))
+	painter.DrawEllipse(ellipse, image.Point{}, 20, 10, 18, 8, nil, painter.Stroke{Color: red, Width: 2})
+	if got := ellipse.RGBAAt(2, 10); got.R < 200 {
+		t.Errorf("ellipse stroke: %v", got)
+	}
+	if got := ellipse.RGBAAt(20, 10); got.A != 0 {
+		t.Errorf("ellipse without fill: %v", got)
+	}
+}
+
+func TestRectangleEffects(t *testing.T) {
+	white := color.RGBA{255, 255, 255, 255}
+
+	// Градиент сверху вниз
+	img := painter.DrawRectangle(painter.Rectangle{
+		Size: image.Point{20, 100},
+		Effects: painter.Effects{
+			FillGradient: painter.VerticalGradient(color.Black, color.White),
+		},
+	})
+	if top, bottom := img.RGBAAt(10, 1).R, img.RGBAAt(10, 98).R; top > 10 || bottom < 245 {
+		t.Errorf("gradient: top %d, bottom %d", top, bottom)
+	}
+
+	// Тень смещена вправо вниз, прямоугольник уменьшен на ее размер
+	img = painter.DrawRectangle(painter.Rectangle{
+		Size:      image.Point{40, 40},
+		FillColor: white,
+		Effects: painter.Effects{
+			Shadow: painter.Shadow{Color: color.Black, Offset: image.Point{4, 4}},
+		},
+	})
+	if got := img.RGBAAt(1, 1); got != white {
+		t.Errorf("rectangle corner: %v", got)
+	}
+	if got := img.RGBAAt(38, 38); got.A != 255 || got.R != 0 {
+		t.Errorf("shadow: %v", got)
+	}
+	if got := img.RGBAAt(1, 38); got.A != 0 {
+		t.Errorf("outside shadow: %v", got)
+	}
+
+	// Внутренняя тень затемняет левый верхний край
+	img = painter.DrawRectangle(painter.Rectangle{
+		Size:      image.Point{40, 40},
+		FillColor: white,
+		Effects: painter.Effects{
+			InnerShadow: painter.Shadow{Color: color.Black, Offset: image.Point{4, 4}},
+		},
+	})
+	if got := img.RGBAAt(1, 20); got.R != 0 {
+		t.Errorf("inner shadow: %v", got)
+	}
+	if got := img.RGBAAt(38, 20); got != white {
+		t.Errorf("opposite edge of inner shadow: %v", got)
+	}
 
-			err = png.Encode(f, img)
-			if err != nil {
-				return
+	// Скруглен только левый верхний угол
+	img = painter.DrawRectangle(painter.Rectangle{
+		Size:      image.Point{40, 40},
+		FillColor: white,
+		Radii:     painter.CornerRadii{TopLeft: 15},
+	})
+	if got := img.RGBAAt(1, 1); got.A != 0 {
+		t.Errorf("rounded corner: %v", got)
+	}
+	if got := img.RGBAAt(38, 1); got != white {
+		t.Errorf("square corner: %v", got)
+	}
+}
+
+func TestRectangleStroke(t *testing.T) {
+	fill := color.RGBA{0, 0, 255, 255}
+	stroke := color.RGBA{128, 0, 0, 128}
+
+	// Ожидаемые цвета пикселей
+	strokeOnFill := color.RGBA{128, 0, 127, 255}
+	strokeOnly := stroke
+	fillOnly := fill
+
+	near := func(a, b color.RGBA) bool {
+		d := func(x, y uint8) bool { return x-y <= 2 || y-x <= 2 }
+		return d(a.R, b.R) && d(a.G, b.G) && d(a.B, b.B) && d(a.A, b.A)
+	}
+
+	sizes := []image.Point{{40, 30}, {60, 50}, {31, 45}}
+	widths := []float64{1, 2, 3, 6, 14}
+	radii := []float64{0, 4, 10}
+	aligns := []painter.StrokeAlign{painter.StrokeInside, painter.StrokeCenter, painter.StrokeOutside}
+
+	for _, size := range sizes {
+		for _, width := range widths {
+			for _, radius := range radii {
+				for _, align := range aligns {
+					img := painter.DrawRectangle(painter.Rectangle{
+						Size:         size,
+						FillColor:    fill,
+						CornerRadius: radius,
+						StrokeWidth:  width,
+						StrokeColor:  stroke,
+						StrokeAlign:  align,
+					})
+
+					// Край заливки отсчитывается от края изображения
+					var edge float64
+					switch align {
+					case painter.StrokeCenter:
+						edge = width / 2
+					case painter.StrokeOutside:
+						edge = width
+					}
+
+					// Ожидаемый цвет пикселя на расстоянии i от края изображения
+					// по середине стороны, false - пиксель на границе
+					expect := func(i int) (color.RGBA, bool) {
+						x := float64(i)
+						if x < edge && x+1 > edge || x < width && x+1 > width {
+							return color.RGBA{}, false
+						}
+						switch {
+						case x < edge:
+							return strokeOnly, true
+						case x < width:
+							return strokeOnFill, true
+						}
+						return fillOnly, true
+					}
+
+					check := func(side string, i int, got color.RGBA) {
+						want, ok := expect(i)
+						if ok && !near(got, want) {
+							t.Errorf("size %v, width %v, radius %v, align %d: %s pixel %d: %v, want %v",
+								size, width, radius, align, side, i, got, want)
+						}
+					}
+
+					// Внешний радиус обводки: радиус фигуры, ограниченный
+					// ее половиной, плюс смещение края
+					var outer float64
+					if radius > 0 {
+						shape := float64(min(size.X, size.Y)) - 2*edge
+						outer = min(radius, shape/2) + edge
+					}
+
+					// Проверяем только прямые участки сторон
+					straight := func(length int) bool {
+						mid := float64(length / 2)
+						return mid >= outer && mid+1 <= float64(length)-outer
+					}
+
+					cx, cy := size.X/2, size.Y/2
+					depth := min(size.X, size.Y)/2 - 1
+					for i := 0; i < depth; i++ {
+						if straight(size.Y) {
+							check("left", i, img.RGBAAt(i, cy))
+							check("right", i, img.RGBAAt(size.X-1-i, cy))
+						}
+						if straight(size.X) {
+							check("top", i, img.RGBAAt(cx, i))
+							check("bottom", i, img.RGBAAt(cx, size.Y-1-i))
+						}
+					}
+
+					// Если обводка шире радиуса, то внутренний угол острый
+					if align == painter.StrokeInside && width >= radius {
+						w := int(width)
+						if got := img.RGBAAt(w, w); !near(got, fillOnly) {
+							t.Errorf("size %v, width %v, radius %v: inner corner %v",
+								size, width, radius, got)
+						}
+					}
+
+					// Скругленный угол прозрачный, острый закрыт обводкой
+					corner := img.RGBAAt(0, 0)
+					if radius > 0 && corner.A != 0 {
+						t.Errorf("size %v, width %v, radius %v, align %d: rounded corner %v",
+							size, width, radius, align, corner)
+					}
+					if radius == 0 && corner.R < stroke.R-2 {
+						t.Errorf("size %v, width %v, radius %v, align %d: square corner %v",
+							size, width, radius, align, corner)
+					}
+				}
 			}
+		}
+	}
+}
 
-		})
+func TestShapeCacheKey(t *testing.T) {
+	rect := painter.Rectangle{
+		Size:         image.Point{30, 20},
+		FillColor:    color.RGBA{255, 0, 0, 255},
+		CornerRadius: 4,
+		Effects:      painter.Effects{FillGradient: painter.VerticalGradient(color.White, color.Black)},
+	}
+	painter.DrawRectangle(rect)
+	hits := rendercache.Statistics().Hits
+
+	// Одинаковые цвета разных типов дают один ключ
+	rect.FillColor = color.NRGBA{255, 0, 0, 255}
+	rect.Effects.FillGradient = painter.VerticalGradient(color.NRGBA{255, 255, 255, 255}, color.Gray{})
+	painter.DrawRectangle(rect)
+	if got := rendercache.Statistics().Hits; got != hits+1 {
+		t.Errorf("equal colors of different types are not cache hits: %d hits, want %d", got, hits+1)
 	}
 }
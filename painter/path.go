@@ -0,0 +1,265 @@
+package painter
+
+import (
+	"image"
+	"image/color"
+	"math"
+	"slices"
+
+	"github.com/srwiley/rasterx"
+	"golang.org/x/image/math/fixed"
+)
+
+// Примитивы рисуются на существующее изображение со смещением at:
+// координаты фигуры отсчитываются от точки at изображения dst.
+// Так на одном изображении можно собрать мнемосхему из труб, емкостей и клапанов.
+// Углы задаются в градусах от оси X по часовой стрелке (ось Y направлена вниз)
+
+// Точка с дробными координатами
+type Point struct {
+	X, Y float64
+}
+
+// Форма концов линии
+type LineCap int
+
+const (
+	CapButt   LineCap = iota // Обрезан по концу линии
+	CapRound                 // Скруглен
+	CapSquare                // Продлен на половину толщины
+)
+
+// Форма соединения отрезков
+type LineJoin int
+
+const (
+	JoinMiter LineJoin = iota // Острый угол, при слишком остром угле - срез
+	JoinRound                 // Скругленный угол
+	JoinBevel                 // Срезанный угол
+)
+
+// Параметры обводки. Если Color nil или Width 0, то обводка не рисуется
+type Stroke struct {
+	Color color.Color
+	Width float64
+	Cap   LineCap
+	Join  LineJoin
+
+	// Длины штрихов и промежутков по очереди, например {10, 5}.
+	// Если не задано, то линия сплошная
+	Dash       []float64
+	DashOffset float64
+}
+
+// Контур из отрезков, кривых Безье и дуг.
+// Контур может состоять из нескольких фигур, каждая начинается с MoveTo
+type Path struct {
+	path    rasterx.Path
+	current Point // Текущая точка
+	open    bool  // Фигура начата
+}
+
+// Начинает новую фигуру в точке x, y
+func (p *Path) MoveTo(x, y float64) {
+	if p.open {
+		p.path.Stop(false)
+	}
+	p.path.Start(rasterx.ToFixedP(x, y))
+	p.current = Point{x, y}
+	p.open = true
+}
+
+// Отрезок из текущей точки в x, y
+func (p *Path) LineTo(x, y float64) {
+	if !p.open {
+		p.MoveTo(p.current.X, p.current.Y)
+	}
+	p.path.Line(rasterx.ToFixedP(x, y))
+	p.current = Point{x, y}
+}
+
+// Квадратичная кривая Безье с контрольной точкой cx, cy
+func (p *Path) QuadTo(cx, cy, x, y float64) {
+	if !p.open {
+		p.MoveTo(p.current.X, p.current.Y)
+	}
+	p.path.QuadBezier(rasterx.ToFixedP(cx, cy), rasterx.ToFixedP(x, y))
+	p.current = Point{x, y}
+}
+
+// Кубическая кривая Безье с контрольными точками c1 и c2
+func (p *Path) CubeTo(c1x, c1y, c2x, c2y, x, y float64) {
+	if !p.open {
+		p.MoveTo(p.current.X, p.current.Y)
+	}
+	p.path.CubeBezier(rasterx.ToFixedP(c1x, c1y), rasterx.ToFixedP(c2x, c2y), rasterx.ToFixedP(x, y))
+	p.current = Point{x, y}
+}
+
+// Дуга окружности с центром cx, cy от угла start на угол sweep.
+// Если фигура начата, то к началу дуги проводится отрезок
+func (p *Path) Arc(cx, cy, r, start, sweep float64) {
+	p.arc(cx, cy, r, r, start, sweep)
+}
+
+// Эллипс как отдельная замкнутая фигура
+func (p *Path) Ellipse(cx, cy, rx, ry float64) {
+	if p.open {
+		p.path.Stop(false)
+		p.open = false
+	}
+	p.arc(cx, cy, rx, ry, 0, 360)
+	p.Close()
+}
+
+// Замыкает текущую фигуру
+func (p *Path) Close() {
+	if !p.open {
+		return
+	}
+	p.path.Stop(true)
+	p.open = false
+}
+
+// Возвращает независимую копию контура
+func (p *Path) Clone() *Path {
+	if p == nil {
+		return nil
+	}
+	c := *p
+	c.path = slices.Clone(p.path)
+	return &c
+}
+
+// Дуга эллипса, приближенная кубическими кривыми Безье не более чем по 90 градусов
+func (p *Path) arc(cx, cy, rx, ry, start, sweep float64) {
+	a := start * math.Pi / 180
+	x, y := cx+rx*math.Cos(a), cy+ry*math.Sin(a)
+	if p.open {
+		p.LineTo(x, y)
+	} else {
+		p.MoveTo(x, y)
+	}
+
+	n := int(math.Ceil(math.Abs(sweep) / 90))
+	if n == 0 {
+		return
+	}
+	step := sweep / float64(n) * math.Pi / 180
+	k := 4.0 / 3.0 * math.Tan(step/4)
+
+	for i := 0; i < n; i++ {
+		b := a + step
+		sinA, cosA := math.Sincos(a)
+		sinB, cosB := math.Sincos(b)
+
+		p.CubeTo(
+			cx+rx*(cosA-k*sinA), cy+ry*(sinA+k*cosA),
+			cx+rx*(cosB+k*sinB), cy+ry*(sinB-k*cosB),
+			cx+rx*cosB, cy+ry*sinB,
+		)
+		a = b
+	}
+}
+
+// Рисует контур: заливает его цветом fill (если не nil) и обводит stroke
+func DrawPath(dst *image.RGBA, at image.Point, p *Path, fill color.Color, s Stroke) {
+	size := dst.Rect.Size()
+	scanner := rasterx.NewScannerGV(size.X, size.Y, dst, dst.Rect)
+
+	// Координаты растеризатора отсчитываются от начала изображения
+	shift := at.Sub(dst.Rect.Min)
+	m := rasterx.Identity.Translate(float64(shift.X), float64(shift.Y))
+
+	if fill != nil {
+		filler := rasterx.NewFiller(size.X, size.Y, scanner)
+		filler.SetColor(fill)
+		p.path.AddTo(&rasterx.MatrixAdder{Adder: filler, M: m})
+		filler.Draw()
+	}
+
+	if s.Color != nil && s.Width > 0 {
+		dasher := rasterx.NewDasher(size.X, size.Y, scanner)
+		dasher.SetColor(s.Color)
+		dasher.SetStroke(fixed.Int26_6(s.Width*64), 4<<6,
+			s.capFunc(), nil, nil, s.joinMode(), s.Dash, s.DashOffset)
+		p.path.AddTo(&rasterx.MatrixAdder{Adder: dasher, M: m})
+		dasher.Draw()
+	}
+}
+
+func (s Stroke) capFunc() rasterx.CapFunc {
+	switch s.Cap {
+	case CapRound:
+		return rasterx.RoundCap
+	case CapSquare:
+		return rasterx.SquareCap
+	}
+	return rasterx.ButtCap
+}
+
+func (s Stroke) joinMode() rasterx.JoinMode {
+	switch s.Join {
+	case JoinRound:
+		return rasterx.Round
+	case JoinBevel:
+		return rasterx.Bevel
+	}
+	return rasterx.Miter
+}
+
+// Отрезок
+func DrawLine(dst *image.RGBA, at image.Point, x1, y1, x2, y2 float64, s Stroke) {
+	var p Path
+	p.MoveTo(x1, y1)
+	p.LineTo(x2, y2)
+	DrawPath(dst, at, &p, nil, s)
+}
+
+// Ломаная через точки points
+func DrawPolyline(dst *image.RGBA, at image.Point, points []Point, s Stroke) {
+	DrawPath(dst, at, polyline(points, false), nil, s)
+}
+
+// Многоугольник с вершинами points
+func DrawPolygon(dst *image.RGBA, at image.Point, points []Point, fill color.Color, s Stroke) {
+	DrawPath(dst, at, polyline(points, true), fill, s)
+}
+
+// Эллипс с центром cx, cy и радиусами rx, ry
+func DrawEllipse(dst *image.RGBA, at image.Point, cx, cy, rx, ry float64, fill color.Color, s Stroke) {
+	var p Path
+	p.Ellipse(cx, cy, rx, ry)
+	DrawPath(dst, at, &p, fill, s)
+}
+
+// Дуга окружности от угла start на угол sweep
+func DrawArc(dst *image.RGBA, at image.Point, cx, cy, r, start, sweep float64, s Stroke) {
+	var p Path
+	p.Arc(cx, cy, r, start, sweep)
+	DrawPath(dst, at, &p, nil, s)
+}
+
+// Сектор круга от угла start на угол sweep
+func DrawPie(dst *image.RGBA, at image.Point, cx, cy, r, start, sweep float64, fill color.Color, s Stroke) {
+	var p Path
+	p.MoveTo(cx, cy)
+	p.Arc(cx, cy, r, start, sweep)
+	p.Close()
+	DrawPath(dst, at, &p, fill, s)
+}
+
+func polyline(points []Point, closed bool) *Path {
+	var p Path
+	for i, pt := range points {
+		if i == 0 {
+			p.MoveTo(pt.X, pt.Y)
+			continue
+		}
+		p.LineTo(pt.X, pt.Y)
+	}
+	if closed {
+		p.Close()
+	}
+	return &p
+}
@@ -0,0 +1,84 @@
+package painter
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"errors"
+	"image"
+	"image/color"
+
+	"github.com/anatolypaw/sgui/rendercache"
+	"github.com/srwiley/oksvg"
+	"github.com/srwiley/rasterx"
+)
+
+// Ключ рендера SVG в кэше. Вместо данных хранится их хэш,
+// чтобы каждый размер и цвет иконки не держал свою копию SVG
+type svgKey struct {
+	data     [sha256.Size]byte
+	size     image.Point
+	color    color.RGBA
+	override bool
+}
+
+// Растеризует SVG иконку в размер size с сохранением пропорций,
+// иконка размещается в центре.
+// Если colorOverride не nil, то все элементы иконки окрашиваются в этот цвет
+// с сохранением прозрачности, так одна монохромная иконка подходит для любой темы.
+// Рендеры хранятся в общем кэше по данным, размеру и цвету,
+// возвращается копия
+func DrawSVG(data []byte, size image.Point, colorOverride color.Color) (*image.RGBA, error) {
+	key := svgKey{data: sha256.Sum256(data), size: size}
+	if colorOverride != nil {
+		key.color = color.RGBAModel.Convert(colorOverride).(color.RGBA)
+		key.override = true
+	}
+
+	// Если иконку не удалось растеризовать, то в кэш ничего не сохраняется
+	var err error
+	img := rendercache.GetCopy(key, func() *image.RGBA {
+		var img *image.RGBA
+		img, err = rasterizeSVG(data, size)
+		if err != nil {
+			return nil
+		}
+		if colorOverride != nil {
+			img = Tint(img, colorOverride)
+		}
+		return img
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return img, nil
+}
+
+// Растеризует SVG без кэширования
+func rasterizeSVG(data []byte, size image.Point) (*image.RGBA, error) {
+	icon, err := oksvg.ReadIconStream(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+
+	vb := icon.ViewBox
+	if vb.W <= 0 || vb.H <= 0 {
+		return nil, errors.New("painter: SVG has no size")
+	}
+
+	if size.X <= 0 || size.Y <= 0 {
+		return image.NewRGBA(image.Rectangle{}), nil
+	}
+	img := image.NewRGBA(image.Rectangle{Max: size})
+
+	// Вписываем иконку в размер с сохранением пропорций
+	scale := min(float64(size.X)/vb.W, float64(size.Y)/vb.H)
+	w, h := vb.W*scale, vb.H*scale
+	icon.SetTarget((float64(size.X)-w)/2, (float64(size.Y)-h)/2, w, h)
+
+	scanner := rasterx.NewScannerGV(size.X, size.Y, img, img.Bounds())
+	dasher := rasterx.NewDasher(size.X, size.Y, scanner)
+	icon.Draw(dasher, 1)
+
+	return img, nil
+}
@@ -0,0 +1,233 @@
+package sgui
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"image"
+	"io"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+// Событие из записи сеанса
+type RecordedEvent struct {
+	Time   time.Duration // Время от начала записи
+	Event  IEvent
+	Screen string // ID экрана, активного в момент события
+}
+
+// Строка файла записи. Файл записи - JSON, одно событие на строку
+type recordLine struct {
+	Time   int64  `json:"t"` // наносекунды от начала записи
+	Type   string `json:"type"`
+	X      int    `json:"x"`
+	Y      int    `json:"y"`
+	Screen string `json:"screen,omitempty"`
+}
+
+const (
+	recordTap     = "tap"
+	recordRelease = "release"
+)
+
+// Запись сеанса: все события, поступающие в Sgui.Event(), записываются в w.
+// Является оберткой над устройством ввода и передается в New() вместо input
+type Recorder struct {
+	input IInput
+	gui   *Sgui
+	start time.Time
+
+	mu  sync.Mutex
+	enc *json.Encoder
+	err error
+}
+
+// Создает запись сеанса и устанавливает ее в gui.Recorder.
+// Тогда записываются и события, которые передаются в Sgui.Event() напрямую,
+// минуя устройство ввода. Если gui nil, то записываются только события input
+func NewRecorder(gui *Sgui, input IInput, w io.Writer) *Recorder {
+	r := &Recorder{
+		input: input,
+		gui:   gui,
+		start: time.Now(),
+		enc:   json.NewEncoder(w),
+	}
+	if gui != nil {
+		gui.Recorder = r
+	}
+	return r
+}
+
+// Получает событие от устройства ввода.
+// Без Sgui событие записывается здесь, иначе его записывает Sgui.Event()
+func (r *Recorder) GetEvent() IEvent {
+	event := r.input.GetEvent()
+	if r.gui == nil {
+		r.record(event, "")
+	}
+	return event
+}
+
+// Записывает событие с ID активного экрана.
+// Записываются только EventTap и EventRelease
+func (r *Recorder) record(event IEvent, screen string) {
+	line := recordLine{
+		Time:   int64(time.Since(r.start)),
+		X:      event.Position().X,
+		Y:      event.Position().Y,
+		Screen: screen,
+	}
+
+	switch event.(type) {
+	case EventTap:
+		line.Type = recordTap
+	case EventRelease:
+		line.Type = recordRelease
+	default:
+		// Другие события не воспроизводятся ReadRecording(), не записываем их
+		return
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.err != nil {
+		return
+	}
+	r.err = r.enc.Encode(line)
+}
+
+// Возвращает первую ошибку записи
+func (r *Recorder) Err() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.err
+}
+
+// Читает запись сеанса
+func ReadRecording(rd io.Reader) ([]RecordedEvent, error) {
+	var events []RecordedEvent
+
+	scanner := bufio.NewScanner(rd)
+	for n := 1; scanner.Scan(); n++ {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+
+		var line recordLine
+		if err := json.Unmarshal(scanner.Bytes(), &line); err != nil {
+			return nil, fmt.Errorf("recording line %d: %w", n, err)
+		}
+
+		pos := image.Point{X: line.X, Y: line.Y}
+		var event IEvent
+		switch line.Type {
+		case recordTap:
+			event = EventTap{Pos: pos}
+		case recordRelease:
+			event = EventRelease{Pos: pos}
+		default:
+			return nil, fmt.Errorf("recording line %d: unknown event type %q", n, line.Type)
+		}
+
+		events = append(events, RecordedEvent{
+			Time:   time.Duration(line.Time),
+			Event:  event,
+			Screen: line.Screen,
+		})
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return events, nil
+}
+
+// Устройство ввода, воспроизводящее запись.
+// Если RealTime, то события выдаются с исходными интервалами,
+// иначе без задержек.
+// После последнего события GetEvent() блокируется, а канал Done() закрывается
+type Player struct {
+	events   []RecordedEvent
+	realTime bool
+	pos      int
+	start    time.Time
+	done     chan struct{}
+}
+
+func NewPlayer(events []RecordedEvent, realTime bool) *Player {
+	return &Player{
+		events:   events,
+		realTime: realTime,
+		done:     make(chan struct{}),
+	}
+}
+
+// Возвращает следующее событие записи
+func (p *Player) GetEvent() IEvent {
+	if p.pos >= len(p.events) {
+		if p.pos == len(p.events) {
+			close(p.done)
+			p.pos++
+		}
+		select {}
+	}
+
+	if p.start.IsZero() {
+		p.start = time.Now()
+	}
+
+	e := p.events[p.pos]
+	p.pos++
+
+	if p.realTime {
+		time.Sleep(time.Until(p.start.Add(e.Time)))
+	}
+
+	return e.Event
+}
+
+// Закрывается, когда все события выданы
+func (p *Player) Done() <-chan struct{} {
+	return p.done
+}
+
+// Параметры воспроизведения записи через Replay()
+type ReplayOptions struct {
+	RealTime bool // Соблюдать исходные интервалы между событиями
+
+	// Если указана, то вызывается после отрисовки кадра,
+	// следующего за каждым событием
+	OnFrame func(n int, e RecordedEvent, display *image.RGBA)
+}
+
+// Воспроизводит запись синхронно: передает событие, отрисовывает кадр.
+// Если активный экран не совпадает с записанным, выводится предупреждение
+func Replay(gui *Sgui, events []RecordedEvent, opt ReplayOptions) {
+	// На время воспроизведения события обрабатываются синхронно,
+	// что бы кадр отражал результат события
+	syncEvents := gui.SyncEvents
+	gui.SyncEvents = true
+	defer func() { gui.SyncEvents = syncEvents }()
+
+	start := time.Now()
+	for n, e := range events {
+		if opt.RealTime {
+			time.Sleep(time.Until(start.Add(e.Time)))
+		}
+
+		if screen := gui.activeScreen(); screen != nil && screen.ID != e.Screen {
+			slog.Warn("SGUI: replay screen mismatch",
+				"event", n, "recorded", e.Screen, "active", screen.ID)
+		}
+
+		gui.Event(e.Event)
+		gui.Render()
+
+		if opt.OnFrame != nil {
+			opt.OnFrame(n, e, gui.Display)
+		}
+	}
+}
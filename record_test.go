@@ -0,0 +1,116 @@
+package sgui
+
+import (
+	"bytes"
+	"image"
+	"io"
+	"testing"
+)
+
+// Устройство ввода, выдающее заранее заданные события
+type scriptInput struct {
+	events []IEvent
+}
+
+func (in *scriptInput) GetEvent() IEvent {
+	e := in.events[0]
+	in.events = in.events[1:]
+	return e
+}
+
+// Виджет, считающий нажатия
+type tapCounter struct {
+	stubWidget
+	taps, releases int
+}
+
+func (w *tapCounter) Tap(image.Point)     { w.taps++ }
+func (w *tapCounter) Release(image.Point) { w.releases++ }
+
+// Событие, неизвестное записи
+type customEvent struct{}
+
+func (customEvent) Position() image.Point { return image.Point{} }
+
+func TestRecordReplay(t *testing.T) {
+	display := image.NewRGBA(image.Rect(0, 0, 50, 50))
+	gui, _ := New(display, nil)
+
+	screen := NewScreen(display.Bounds())
+	screen.ID = "main"
+	gui.SetScreen(&screen)
+
+	var buf bytes.Buffer
+	rec := NewRecorder(&gui, &scriptInput{events: []IEvent{
+		EventTap{Pos: image.Point{5, 5}},
+		EventRelease{Pos: image.Point{6, 6}},
+		customEvent{},
+		EventTap{Pos: image.Point{40, 40}},
+	}}, &buf)
+
+	for i := 0; i < 4; i++ {
+		gui.Event(rec.GetEvent())
+	}
+
+	// События, переданные напрямую, тоже записываются
+	other := NewScreen(display.Bounds())
+	other.ID = "other"
+	gui.SetScreen(&other)
+	gui.Event(EventRelease{Pos: image.Point{1, 1}})
+	gui.SetScreen(&screen)
+
+	if err := rec.Err(); err != nil {
+		t.Fatalf("Recorder: %v", err)
+	}
+
+	events, err := ReadRecording(&buf)
+	if err != nil {
+		t.Fatalf("ReadRecording: %v", err)
+	}
+	if len(events) != 4 {
+		t.Fatalf("got %d events, want 4", len(events))
+	}
+	if events[1].Event != (EventRelease{Pos: image.Point{6, 6}}) || events[1].Screen != "main" {
+		t.Fatalf("unexpected event: %+v", events[1])
+	}
+	if events[3].Event != (EventRelease{Pos: image.Point{1, 1}}) || events[3].Screen != "other" {
+		t.Fatalf("unexpected direct event: %+v", events[3])
+	}
+
+	w := &tapCounter{stubWidget: stubWidget{size: image.Point{10, 10}}}
+	screen.AddWidget(0, 0, w)
+
+	frames := 0
+	Replay(&gui, events, ReplayOptions{
+		OnFrame: func(int, RecordedEvent, *image.RGBA) { frames++ },
+	})
+
+	if w.taps != 1 || w.releases != 2 || frames != 4 {
+		t.Fatalf("taps=%d releases=%d frames=%d, want 1 2 4", w.taps, w.releases, frames)
+	}
+}
+
+func TestRecordScreenChange(t *testing.T) {
+	display := image.NewRGBA(image.Rect(0, 0, 50, 50))
+	gui, _ := New(display, nil)
+	gui.SyncEvents = true
+	NewRecorder(&gui, nil, io.Discard)
+
+	a, b := NewScreen(display.Bounds()), NewScreen(display.Bounds())
+	a.ID, b.ID = "a", "b"
+	gui.SetScreen(&a)
+
+	// Смена экрана во время записи событий
+	done := make(chan struct{})
+	go func() {
+		for i := 0; i < 100; i++ {
+			gui.Event(EventTap{Pos: image.Point{1, 1}})
+		}
+		close(done)
+	}()
+	for i := 0; i < 100; i++ {
+		gui.SetScreen(&b)
+		gui.SetScreen(&a)
+	}
+	<-done
+}
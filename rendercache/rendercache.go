@@ -0,0 +1,172 @@
+// Общий кэш растровых изображений для painter и text2img.
+// Одинаковые фигуры и надписи рисуются один раз: экран с сотнями
+// одинаковых индикаторов строится и перекрашивается темой быстро.
+// Кэш ограничен по памяти, при переполнении удаляются
+// давно не использованные изображения (LRU).
+
+package rendercache
+
+import (
+	"container/list"
+	"image"
+	"sync"
+)
+
+// Объем памяти под изображения по умолчанию, байт.
+// Учитываются только пиксели изображений (Pix), память ключей
+// и служебных структур в ограничение не входит
+const DefaultBudget = 32 << 20
+
+// Статистика кэша
+type Stats struct {
+	Hits      uint64 // Изображение найдено в кэше
+	Misses    uint64 // Изображение пришлось рисовать
+	Evictions uint64 // Изображение удалено из-за нехватки памяти
+	Entries   int    // Изображений в кэше
+	Bytes     int    // Памяти занято пикселями изображений
+	Budget    int    // Ограничение памяти
+}
+
+type entry struct {
+	key   any
+	img   *image.RGBA
+	bytes int
+}
+
+type cache struct {
+	mu      sync.Mutex
+	budget  int
+	bytes   int
+	order   *list.List // Элементы от недавно использованных к давно
+	entries map[any]*list.Element
+	stats   Stats
+}
+
+var std = newCache(DefaultBudget)
+
+func newCache(budget int) *cache {
+	return &cache{
+		budget:  budget,
+		order:   list.New(),
+		entries: make(map[any]*list.Element),
+	}
+}
+
+// Возвращает изображение по ключу key. Если его нет в кэше,
+// то оно рисуется функцией render и сохраняется.
+// Ключ должен быть сравнимым значением, в которое входят
+// все параметры рисования.
+// Возвращаемое изображение общее, его нельзя изменять
+func Get(key any, render func() *image.RGBA) *image.RGBA {
+	if img, ok := Load(key); ok {
+		return img
+	}
+	img := render()
+	Store(key, img)
+	return img
+}
+
+// Как Get, но возвращает копию изображения, которую можно изменять
+func GetCopy(key any, render func() *image.RGBA) *image.RGBA {
+	return clone(Get(key, render))
+}
+
+// Возвращает изображение по ключу key и true, если оно есть в кэше.
+// Возвращаемое изображение общее, его нельзя изменять
+func Load(key any) (*image.RGBA, bool) {
+	return std.load(key)
+}
+
+// Сохраняет изображение по ключу key.
+// Изображение больше ограничения памяти не сохраняется
+func Store(key any, img *image.RGBA) {
+	std.store(key, img)
+}
+
+// Устанавливает ограничение памяти под пиксели изображений в байтах.
+// Лишние изображения сразу удаляются, 0 отключает кэш
+func SetBudget(bytes int) {
+	std.mu.Lock()
+	defer std.mu.Unlock()
+	std.budget = max(0, bytes)
+	std.evict()
+}
+
+// Удаляет все изображения и обнуляет статистику
+func Reset() {
+	std.mu.Lock()
+	defer std.mu.Unlock()
+	std.order.Init()
+	std.entries = make(map[any]*list.Element)
+	std.bytes = 0
+	std.stats = Stats{}
+}
+
+// Возвращает статистику кэша
+func Statistics() Stats {
+	std.mu.Lock()
+	defer std.mu.Unlock()
+	s := std.stats
+	s.Entries = len(std.entries)
+	s.Bytes = std.bytes
+	s.Budget = std.budget
+	return s
+}
+
+func (c *cache) load(key any) (*image.RGBA, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	e, ok := c.entries[key]
+	if !ok {
+		c.stats.Misses++
+		return nil, false
+	}
+	c.stats.Hits++
+	c.order.MoveToFront(e)
+	return e.Value.(*entry).img, true
+}
+
+func (c *cache) store(key any, img *image.RGBA) {
+	if img == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if e, ok := c.entries[key]; ok {
+		c.remove(e)
+	}
+
+	n := len(img.Pix)
+	if n > c.budget {
+		return
+	}
+	c.entries[key] = c.order.PushFront(&entry{key: key, img: img, bytes: n})
+	c.bytes += n
+	c.evict()
+}
+
+// Удаляет давно не использованные изображения, пока память превышает ограничение
+func (c *cache) evict() {
+	for c.bytes > c.budget {
+		c.remove(c.order.Back())
+		c.stats.Evictions++
+	}
+}
+
+func (c *cache) remove(e *list.Element) {
+	en := c.order.Remove(e).(*entry)
+	delete(c.entries, en.key)
+	c.bytes -= en.bytes
+}
+
+// Возвращает копию изображения
+func clone(img *image.RGBA) *image.RGBA {
+	if img == nil {
+		return nil
+	}
+	out := image.NewRGBA(img.Rect)
+	copy(out.Pix, img.Pix)
+	return out
+}
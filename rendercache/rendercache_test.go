@@ -0,0 +1,59 @@
+package rendercache
+
+import (
+	"image"
+	"testing"
+)
+
+func TestCache(t *testing.T) {
+	Reset()
+	defer SetBudget(DefaultBudget)
+
+	// Изображение 10x10 занимает 400 байт, в кэш помещаются два
+	SetBudget(800)
+
+	renders := 0
+	render := func() *image.RGBA {
+		renders++
+		return image.NewRGBA(image.Rect(0, 0, 10, 10))
+	}
+
+	a := Get("a", render)
+	if Get("a", render) != a || renders != 1 {
+		t.Fatalf("cached image was rendered again: %d renders", renders)
+	}
+
+	// Копию можно изменять, кэш от этого не меняется
+	c := GetCopy("a", render)
+	c.Pix[0] = 255
+	if a.Pix[0] != 0 {
+		t.Error("GetCopy returned the cached image")
+	}
+
+	Get("b", render)
+	Get("a", render) // a использован позже b
+	Get("c", render) // вытесняет b
+	if _, ok := Load("b"); ok {
+		t.Error("least recently used image was not evicted")
+	}
+	if _, ok := Load("a"); !ok {
+		t.Error("recently used image was evicted")
+	}
+
+	s := Statistics()
+	want := Stats{Hits: 4, Misses: 4, Evictions: 1, Entries: 2, Bytes: 800, Budget: 800}
+	if s != want {
+		t.Errorf("stats %+v, want %+v", s, want)
+	}
+
+	// Изображение больше ограничения не сохраняется
+	Get("big", func() *image.RGBA { return image.NewRGBA(image.Rect(0, 0, 100, 100)) })
+	if _, ok := Load("big"); ok {
+		t.Error("image larger than budget was cached")
+	}
+
+	SetBudget(0)
+	if s := Statistics(); s.Entries != 0 || s.Bytes != 0 {
+		t.Errorf("cache not emptied: %+v", s)
+	}
+}
@@ -31,137 +31,101 @@ func main() {
 	// Создаем гуй
 	gui, _ := sgui.New(display, nil)
 
-	// Создаем тему
-	theme := widget.ColorTheme{
+	// Создаем темы: дневную и ночную
+	dayTheme := widget.ThemeFromColors("day", widget.ColorTheme{
 		BackgroundColor: color.RGBA{240, 240, 240, 255},
 		MainColor:       color.RGBA{200, 200, 200, 255},
 		SecondColor:     color.RGBA{180, 180, 180, 255},
+		TextColor:       color.Black,
 		StrokeColor:     color.RGBA{60, 60, 60, 255},
 		StrokeWidth:     2,
 		CornerRadius:    10,
-	}
+	})
+	nightTheme := widget.ThemeFromColors("night", widget.ColorTheme{
+		BackgroundColor: color.RGBA{30, 30, 35, 255},
+		MainColor:       color.RGBA{60, 60, 70, 255},
+		SecondColor:     color.RGBA{90, 90, 100, 255},
+		TextColor:       color.RGBA{220, 220, 220, 255},
+		StrokeColor:     color.RGBA{150, 150, 160, 255},
+		StrokeWidth:     2,
+		CornerRadius:    10,
+	})
+	widget.SetTheme(dayTheme)
 
 	// Создаем экраны
 	mainScreen := sgui.NewScreen(gui.SizeDisplay())
-	mainScreen.SetBackground(theme.BackgroundColor)
+	mainScreen.SetBackground(dayTheme.Background)
 
 	secondScreen := sgui.NewScreen(gui.SizeDisplay())
-	secondScreen.SetBackground(theme.BackgroundColor)
+	secondScreen.SetBackground(dayTheme.Background)
+
+	// При смене темы меняем фон экранов
+	widget.OnThemeChange(func(t *widget.Theme) {
+		mainScreen.SetBackground(t.Background)
+		secondScreen.SetBackground(t.Background)
+	})
 
 	// Создаем виджеты на основной экран
-	ind := widget.NewIndicator(20, nil, theme)
-	ind.AddState(color.RGBA{255, 0, 0, 255})
+	ind := widget.NewThemedIndicator(20, nil)
+	ind.AddThemeState(widget.StateAlarm)
 	ind.AddState(color.RGBA{0, 255, 0, 255})
 
 	counter := 0
 
-	label := widget.NewLabel(
-		&widget.LabelParam{
-			Size:            image.Point{500, 200},
-			Text:            fmt.Sprint(counter),
-			TextSize:        200,
-			TextColor:       color.Black,
-			FillColor:       nil,
-			BackgroundColor: theme.BackgroundColor,
-			CornerRadius:    0,
-			StrokeWidth:     theme.StrokeWidth,
-			StrokeColor:     theme.StrokeColor,
-		},
-		nil)
+	dayTheme.SetStyle("label.counter", widget.StateNormal,
+		widget.Style{TextColor: color.Black, TextSize: 200})
+	nightTheme.SetStyle("label.counter", widget.StateNormal,
+		widget.Style{TextColor: color.RGBA{255, 200, 0, 255}, TextSize: 200})
+
+	label := widget.NewThemedLabel(image.Point{500, 200}, fmt.Sprint(counter))
+	label.SetClass("label.counter")
 
 	mainScreen.AddWidget(200, 200, label)
 
-	button2 := widget.NewButton(
-		&widget.ButtonParam{
-			Size: image.Point{X: 110, Y: 40},
-			OnClick: func() {
-				counter++
-				label.SetText(fmt.Sprint(counter), 200, color.Black)
-				if ind.GetState() == 0 {
-					ind.SetState(1)
-				} else {
-					ind.SetState(0)
-				}
-			},
-			Text:             "Button 2",
-			TextSize:         20,
-			ReleaseFillColor: theme.MainColor,
-			PressFillColor:   theme.SecondColor,
-			BackgroundColor:  theme.BackgroundColor,
-			CornerRadius:     theme.CornerRadius,
-			StrokeWidth:      theme.StrokeWidth,
-			StrokeColor:      theme.StrokeColor,
-			TextColor:        theme.TextColor,
-		},
-		nil,
-	)
-
-	button1 := widget.NewButton(
-		&widget.ButtonParam{
-			Size: image.Point{X: 110, Y: 40},
-			OnClick: func() {
-				if button2.Hidden() {
-					button2.Show()
-				} else {
-					button2.Hide()
-				}
-			},
-			Text:             "Hide",
-			TextSize:         20,
-			ReleaseFillColor: theme.MainColor,
-			PressFillColor:   theme.SecondColor,
-			BackgroundColor:  theme.BackgroundColor,
-			CornerRadius:     theme.CornerRadius,
-			StrokeWidth:      theme.StrokeWidth,
-			StrokeColor:      theme.StrokeColor,
-			TextColor:        theme.TextColor,
-		},
-		nil,
-	)
-
-	buttonSetSecondScreen := widget.NewButton(
-		&widget.ButtonParam{
-			Size: image.Point{X: 110, Y: 40},
-			OnClick: func() {
-				gui.SetScreen(&secondScreen)
-			},
-			Text:             "2 экран",
-			TextSize:         20,
-			ReleaseFillColor: theme.MainColor,
-			PressFillColor:   theme.SecondColor,
-			BackgroundColor:  theme.BackgroundColor,
-			CornerRadius:     theme.CornerRadius,
-			StrokeWidth:      theme.StrokeWidth,
-			StrokeColor:      theme.StrokeColor,
-			TextColor:        theme.TextColor,
-		},
-		nil,
-	)
-
-	buttonSetMainScreen := widget.NewButton(
-		&widget.ButtonParam{
-			Size: image.Point{X: 110, Y: 40},
-			OnClick: func() {
-				gui.SetScreen(&mainScreen)
-			},
-			Text:             "1 экран",
-			TextSize:         20,
-			ReleaseFillColor: theme.MainColor,
-			PressFillColor:   theme.SecondColor,
-			BackgroundColor:  theme.BackgroundColor,
-			CornerRadius:     theme.CornerRadius,
-			StrokeWidth:      theme.StrokeWidth,
-			StrokeColor:      theme.StrokeColor,
-			TextColor:        theme.TextColor,
-		},
-		nil,
-	)
+	button2 := widget.NewThemedButton(image.Point{X: 110, Y: 40}, "Button 2", func() {
+		counter++
+		label.SetCaption(fmt.Sprint(counter))
+		if ind.GetState() == 0 {
+			ind.SetState(1)
+		} else {
+			ind.SetState(0)
+		}
+	})
+
+	button1 := widget.NewThemedButton(image.Point{X: 110, Y: 40}, "Hide", func() {
+		if button2.Hidden() {
+			button2.Show()
+		} else {
+			button2.Hide()
+		}
+	})
+
+	buttonTheme := widget.NewThemedButton(image.Point{X: 110, Y: 40}, "День/ночь", func() {
+		if widget.CurrentTheme() == dayTheme {
+			widget.SetTheme(nightTheme)
+		} else {
+			widget.SetTheme(dayTheme)
+		}
+	})
+
+	// Переключатель: каждое нажатие меняет состояние
+	pump := widget.NewThemedToggleButton(image.Point{X: 110, Y: 40}, "Насос", "Насос вкл", nil)
+
+	buttonSetSecondScreen := widget.NewThemedButton(image.Point{X: 110, Y: 40}, "2 экран", func() {
+		gui.SetScreen(&secondScreen)
+	})
+
+	buttonSetMainScreen := widget.NewThemedButton(image.Point{X: 110, Y: 40}, "1 экран", func() {
+		gui.SetScreen(&mainScreen)
+	})
 
 	// Добавляем виджеты на холст
 	mainScreen.AddWidget(10, 10, button1)
 	mainScreen.AddWidget(10, 60, button2)
 	mainScreen.AddWidget(130, 70, ind)
-	mainScreen.AddWidget(10, 200, buttonSetSecondScreen)
+	mainScreen.AddWidget(10, 110, buttonTheme)
+	mainScreen.AddWidget(10, 160, pump)
+	mainScreen.AddWidget(10, 210, buttonSetSecondScreen)
 
 	secondScreen.AddWidget(10, 10, buttonSetMainScreen)
 
@@ -1,27 +1,87 @@
 package sgui
 
 import (
+	"fmt"
 	"image"
 	"image/color"
+	"io"
+	"log/slog"
 	"sync"
 
+	"github.com/anatolypaw/sgui/anim"
 	"github.com/anatolypaw/sgui/painter"
 )
 
 // На экране размещаются различные виджеты
 type Screen struct {
+	ID               string            // Необязательный идентификатор, сохраняется в записи событий
 	Background       *image.RGBA       // Изображение бэкграунда
 	Objects          []Object          // виджеты и их положение на дисплее
 	TapHooker        func(image.Point) // Если указан, то вызывается при нажатии в любом месте экрана
 	RunOnce          func()            // Запускается один раз при установке экрана активным
 	Size             image.Rectangle
 	BackgroundRefill bool
-	mu               sync.Mutex // Блокировка, когда идет работа с экраном.
+	mu               sync.Mutex        // Блокировка, когда идет работа с экраном.
+	damage           []image.Rectangle // Области, которые нужно перерисовать
+	clock            *anim.Clock       // Часы Sgui, в котором установлен экран
+	group            *screenGroup      // Виджеты экранов Sgui, в котором установлен экран
+}
+
+// Виджеты всех экранов одного Sgui. При удалении виджета с экрана
+// его таймеры останавливаются, только если он не остался на других экранах
+type screenGroup struct {
+	mu      sync.Mutex
+	widgets map[IWidget]int // Сколько раз виджет размещен на экранах
+}
+
+func newScreenGroup() *screenGroup {
+	return &screenGroup{widgets: make(map[IWidget]int)}
+}
+
+func (g *screenGroup) add(w IWidget) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.widgets[w]++
+}
+
+// Уменьшает счетчик виджета.
+// Возвращает true, если виджета больше нет ни на одном экране
+func (g *screenGroup) remove(w IWidget) bool {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	n := g.widgets[w] - 1
+	if n <= 0 {
+		delete(g.widgets, w)
+		return true
+	}
+	g.widgets[w] = n
+	return false
 }
 
 type Object struct {
+	ID       string // Необязательный идентификатор, для поиска через Find()
 	Widget   IWidget
 	Position image.Point
+
+	// Прозрачность объекта: 0 - непрозрачный, 1 - невидимый.
+	// Хранится как прозрачность, что бы нулевое значение было непрозрачным.
+	// Устанавливается через Screen.SetOpacity()
+	transparency float64
+
+	// Область, в которой объект был отрисован в последнем кадре.
+	// Пустая, если объект был скрыт или еще не отрисовывался
+	drawn image.Rectangle
+}
+
+// Возвращает непрозрачность объекта: 1 - непрозрачный, 0 - невидимый
+func (o *Object) Opacity() float64 {
+	return 1 - o.transparency
+}
+
+// Возвращает область дисплея, занимаемую объектом
+func (o *Object) rect() image.Rectangle {
+	return image.Rectangle{Max: o.Widget.Size()}.Add(o.Position)
 }
 
 // -
@@ -34,10 +94,21 @@ type IWidget interface {
 	Hide()          // Включает флаг скрытия виджета
 	Show()          // Отключает флаг скрытия виджета
 	Hidden() bool   // Возвращает флаг, скрыт ли виджет
+	Enable()        // Включает виджет, он воспринимает события
+	Disable()       // Отключает виджет, он отображается приглушенным и не воспринимает события
 	Disabled() bool // Возвращает флаг, воспринимает ли виджет события
 	Update()        // обновляет внутрнее состояние виджета
 }
 
+// Виджет с таймерами и анимациями по часам кадров.
+// Sgui привязывает его к своим часам при отрисовке.
+// При удалении с последнего экрана Sgui виджет отвязывается (SetClock(nil))
+// и его таймеры останавливаются
+type ITimedWidget interface {
+	IWidget
+	SetClock(*anim.Clock)
+}
+
 // Создает экран
 func NewScreen(size image.Rectangle) Screen {
 	return Screen{
@@ -48,16 +119,219 @@ func NewScreen(size image.Rectangle) Screen {
 
 // Добавляет объект (widget) на экран
 func (ui *Screen) AddWidget(x int, y int, w IWidget) {
+	ui.mu.Lock()
+	defer ui.mu.Unlock()
+
+	obj := Object{
+		Widget:   w,
+		Position: image.Point{X: x, Y: y},
+	}
+	ui.Objects = append(ui.Objects, obj)
+	ui.attach(w)
+}
+
+// Добавляет объект (widget) с идентификатором на экран
+func (ui *Screen) AddWidgetID(id string, x int, y int, w IWidget) {
+	ui.mu.Lock()
+	defer ui.mu.Unlock()
+
 	obj := Object{
+		ID:       id,
 		Widget:   w,
 		Position: image.Point{X: x, Y: y},
 	}
 	ui.Objects = append(ui.Objects, obj)
+	ui.attach(w)
+}
+
+// Вставляет объект в позицию index порядка отрисовки.
+// 0 - самый нижний слой. Если index за пределами списка,
+// то объект добавляется поверх всех
+func (ui *Screen) Insert(index int, obj Object) {
+	ui.mu.Lock()
+	defer ui.mu.Unlock()
+
+	if index < 0 {
+		index = 0
+	}
+
+	// Объект еще не отрисован на этом экране
+	obj.drawn = image.Rectangle{}
+	ui.attach(obj.Widget)
+
+	if index >= len(ui.Objects) {
+		ui.Objects = append(ui.Objects, obj)
+		return
+	}
+
+	ui.Objects = append(ui.Objects, Object{})
+	copy(ui.Objects[index+1:], ui.Objects[index:])
+	ui.Objects[index] = obj
+}
+
+// Возвращает виджет по идентификатору, либо nil, если он не найден
+func (ui *Screen) Find(id string) IWidget {
+	ui.mu.Lock()
+	defer ui.mu.Unlock()
+
+	i := ui.indexByID(id)
+	if i < 0 {
+		return nil
+	}
+	return ui.Objects[i].Widget
+}
+
+// Удаляет виджет с экрана. Если виджета не осталось на других экранах
+// этого Sgui, то его таймеры останавливаются.
+// Возвращает false, если виджета на экране нет
+func (ui *Screen) RemoveWidget(w IWidget) bool {
+	ui.mu.Lock()
+	defer ui.mu.Unlock()
+
+	i := ui.indexByWidget(w)
+	if i < 0 {
+		return false
+	}
+
+	// Виджет нужно стереть с дисплея
+	ui.invalidate(ui.Objects[i].drawn)
+	ui.Objects = append(ui.Objects[:i], ui.Objects[i+1:]...)
+
+	if ui.group == nil || !ui.group.remove(w) {
+		return true
+	}
+	if tw, ok := w.(ITimedWidget); ok {
+		tw.SetClock(nil)
+	}
+	return true
+}
+
+// Перемещает виджет на верхний слой, он будет отрисован поверх остальных.
+// Возвращает false, если виджета на экране нет
+func (ui *Screen) RaiseToTop(w IWidget) bool {
+	ui.mu.Lock()
+	defer ui.mu.Unlock()
+
+	i := ui.indexByWidget(w)
+	if i < 0 {
+		return false
+	}
+
+	obj := ui.Objects[i]
+	ui.Objects = append(ui.Objects[:i], ui.Objects[i+1:]...)
+	ui.Objects = append(ui.Objects, obj)
+
+	ui.invalidate(obj.drawn)
+	return true
+}
+
+// Перемещает виджет в новое положение.
+// Возвращает false, если виджета на экране нет
+func (ui *Screen) SetPosition(w IWidget, pos image.Point) bool {
+	ui.mu.Lock()
+	defer ui.mu.Unlock()
+
+	i := ui.indexByWidget(w)
+	if i < 0 {
+		return false
+	}
+
+	// Старая и новая области перерисуются при отрисовке,
+	// так как изменится область объекта
+	ui.Objects[i].Position = pos
+	return true
+}
+
+// Устанавливает непрозрачность виджета: 1 - непрозрачный, 0 - невидимый.
+// Возвращает false, если виджета на экране нет
+func (ui *Screen) SetOpacity(w IWidget, opacity float64) bool {
+	ui.mu.Lock()
+	defer ui.mu.Unlock()
+
+	i := ui.indexByWidget(w)
+	if i < 0 {
+		return false
+	}
+
+	opacity = min(max(opacity, 0), 1)
+	if ui.Objects[i].Opacity() != opacity {
+		ui.Objects[i].transparency = 1 - opacity
+		ui.invalidate(ui.Objects[i].drawn)
+	}
+	return true
+}
+
+// Привязывает виджет к часам Sgui, в котором установлен экран
+func (ui *Screen) attach(w IWidget) {
+	if ui.group != nil {
+		ui.group.add(w)
+	}
+	ui.bind(w)
+}
+
+// Привязывает виджет к часам экрана
+func (ui *Screen) bind(w IWidget) {
+	if tw, ok := w.(ITimedWidget); ok && ui.clock != nil {
+		tw.SetClock(ui.clock)
+	}
+}
+
+// Отмечает область экрана для перерисовки в следующем кадре
+func (ui *Screen) invalidate(r image.Rectangle) {
+	ui.damage = addDamage(ui.damage, r)
+}
+
+// Выводит дерево виджетов экрана, для отладки.
+// Виджеты перечисляются в порядке отрисовки, снизу вверх
+func (ui *Screen) Dump(out io.Writer) {
+	ui.mu.Lock()
+	defer ui.mu.Unlock()
+
+	fmt.Fprintf(out, "Screen %q %dx%d, objects: %d, background refill: %v\n",
+		ui.ID, ui.Size.Dx(), ui.Size.Dy(), len(ui.Objects), ui.BackgroundRefill)
+
+	for i, o := range ui.Objects {
+		id := o.ID
+		if id == "" {
+			id = "-"
+		}
+		size := o.Widget.Size()
+		fmt.Fprintf(out, "  %d: id=%s type=%T pos=%d,%d size=%dx%d opacity=%.2f hidden=%v disabled=%v updated=%v\n",
+			i, id, o.Widget,
+			o.Position.X, o.Position.Y,
+			size.X, size.Y,
+			o.Opacity(),
+			o.Widget.Hidden(), o.Widget.Disabled(), o.Widget.Updated(),
+		)
+	}
+}
+
+// Возвращает индекс объекта с заданным идентификатором, либо -1
+func (ui *Screen) indexByID(id string) int {
+	if id == "" {
+		return -1
+	}
+	for i, o := range ui.Objects {
+		if o.ID == id {
+			return i
+		}
+	}
+	return -1
+}
+
+// Возвращает индекс объекта с заданным виджетом, либо -1
+func (ui *Screen) indexByWidget(w IWidget) int {
+	for i, o := range ui.Objects {
+		if o.Widget == w {
+			return i
+		}
+	}
+	return -1
 }
 
 // Заливка заднего фона сплошным цветом
 func (ths *Screen) SetBackground(c color.Color) {
-	ths.Background = painter.DrawRectangle(
+	ths.setBackground(painter.DrawRectangle(
 		painter.Rectangle{
 			Size: image.Point{
 				ths.Size.Dx(),
@@ -65,6 +339,50 @@ func (ths *Screen) SetBackground(c color.Color) {
 			},
 			FillColor: c,
 		},
-	)
+	))
+}
+
+// Устанавливает изображение заднего фона, вписанное в размер экрана способом mode.
+// Области, не закрытые изображением, заливаются цветом fill
+func (ths *Screen) SetBackgroundImage(img image.Image, mode painter.ScaleMode, fill color.Color) {
+	ths.setBackground(painter.ScaleImage(img, ths.Size.Size(), mode, fill))
+}
+
+// Устанавливает изображение заднего фона из данных PNG, JPEG или GIF
+func (ths *Screen) LoadBackgroundImage(data []byte, mode painter.ScaleMode, fill color.Color) error {
+	img, err := painter.DecodeImage(data)
+	if err != nil {
+		return fmt.Errorf("background image: %w", err)
+	}
+	ths.SetBackgroundImage(img, mode, fill)
+	return nil
+}
+
+// Заливка заднего фона линейным или радиальным градиентом
+func (ths *Screen) SetBackgroundGradient(g painter.Gradient) {
+	ths.setBackground(painter.DrawGradient(ths.Size.Size(), g))
+}
+
+// Заменяет задний фон и перерисовывает экран целиком
+func (ths *Screen) setBackground(img *image.RGBA) {
+	ths.mu.Lock()
+	defer ths.mu.Unlock()
+
+	ths.Background = img
+	ths.BackgroundRefill = true
+}
+
+// Проверяет, что задний фон совпадает с размером экрана.
+// Если нет, то растягивает его до размера экрана
+func (ths *Screen) fitBackground() {
+	if ths.Background == nil || ths.Background.Bounds().Size() == ths.Size.Size() {
+		return
+	}
+
+	slog.Warn("SGUI: background size differs from screen, stretching",
+		"screen", ths.ID,
+		"background", ths.Background.Bounds().Size(),
+		"size", ths.Size.Size())
 
+	ths.Background = painter.ScaleImage(ths.Background, ths.Size.Size(), painter.ScaleStretch, nil)
 }
@@ -0,0 +1,73 @@
+package sgui
+
+import (
+	"image"
+	"strings"
+	"testing"
+)
+
+// Виджет-заглушка для тестов
+type stubWidget struct {
+	size     image.Point
+	hidden   bool
+	disabled bool
+}
+
+func (w *stubWidget) Render() *image.RGBA { return image.NewRGBA(image.Rectangle{Max: w.size}) }
+func (w *stubWidget) Size() image.Point   { return w.size }
+func (w *stubWidget) Updated() bool       { return false }
+func (w *stubWidget) Tap(image.Point)     {}
+func (w *stubWidget) Release(image.Point) {}
+func (w *stubWidget) Hide()               { w.hidden = true }
+func (w *stubWidget) Show()               { w.hidden = false }
+func (w *stubWidget) Hidden() bool        { return w.hidden }
+func (w *stubWidget) Enable()             { w.disabled = false }
+func (w *stubWidget) Disable()            { w.disabled = true }
+func (w *stubWidget) Disabled() bool      { return w.disabled }
+func (w *stubWidget) Update()             {}
+
+func TestScreenTree(t *testing.T) {
+	screen := NewScreen(image.Rect(0, 0, 100, 100))
+
+	a := &stubWidget{size: image.Point{10, 10}}
+	b := &stubWidget{size: image.Point{20, 20}}
+	c := &stubWidget{size: image.Point{30, 30}}
+
+	screen.AddWidgetID("a", 0, 0, a)
+	screen.AddWidget(5, 5, b)
+	screen.Insert(0, Object{ID: "c", Widget: c})
+
+	if got := screen.Find("a"); got != a {
+		t.Fatalf("Find(a) = %v, want %v", got, a)
+	}
+	if got := screen.Find("nope"); got != nil {
+		t.Fatalf("Find(nope) = %v, want nil", got)
+	}
+	if screen.Objects[0].Widget != c {
+		t.Fatalf("Insert(0) did not place widget at the bottom")
+	}
+
+	if !screen.RaiseToTop(c) {
+		t.Fatalf("RaiseToTop(c) = false")
+	}
+	if screen.Objects[len(screen.Objects)-1].Widget != c {
+		t.Fatalf("RaiseToTop did not move widget to the top")
+	}
+
+	if !screen.RemoveWidget(a) {
+		t.Fatalf("RemoveWidget(a) = false")
+	}
+	if screen.RemoveWidget(a) {
+		t.Fatalf("second RemoveWidget(a) = true")
+	}
+	if screen.Find("a") != nil {
+		t.Fatalf("removed widget is still found")
+	}
+
+	var sb strings.Builder
+	screen.Dump(&sb)
+	dump := sb.String()
+	if !strings.Contains(dump, "id=c") || !strings.Contains(dump, "size=20x20") {
+		t.Fatalf("unexpected dump:\n%s", dump)
+	}
+}
@@ -3,9 +3,16 @@ package sgui
 import (
 	_ "fmt"
 	"image"
+	"image/color"
 	"image/draw"
 	"log"
 	_ "log"
+	"math"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/anatolypaw/sgui/anim"
 )
 
 // Основа. Отображает экраны.
@@ -15,6 +22,25 @@ type Sgui struct {
 	InputDevice  IInput      // Устройство ввода
 	ActiveScreen *Screen     // Активный экран, который будет обрабатываться
 	Overlay      *Overlay    // Отрисовывается поверх всех экранов, не
+
+	// Если true, то события передаются виджетам синхронно,
+	// в горутине вызвавшей Event(). Используется в тестах
+	SyncEvents bool
+
+	// Источник времени кадров. Если не указан, используется time.Now.
+	// Подменяется в тестах для воспроизводимых анимаций
+	TimeSource func() time.Time
+
+	// Часы кадров. Виджеты с таймерами (ITimedWidget) привязываются
+	// к ним при отрисовке. Если не указаны, создаются при первой отрисовке
+	Clock *anim.Clock
+
+	// Если указан, то все события, поступающие в Event(), записываются.
+	// Устанавливается NewRecorder()
+	Recorder *Recorder
+
+	screens *screenGroup // Виджеты экранов, которые устанавливались активными
+	mu      sync.Mutex   // Блокировка смены активного экрана
 }
 
 // Интерфейс устройства ввода
@@ -26,28 +52,72 @@ func New(display *image.RGBA, input IInput) (Sgui, error) {
 	return Sgui{
 		Display:     display,
 		InputDevice: input,
+		Clock:       anim.NewClock(time.Now()),
+		screens:     newScreenGroup(),
 	}, nil
 }
 
 // Устанавливает активный экран
 func (ths *Sgui) SetScreen(screen *Screen) {
+	if ths.Clock == nil {
+		ths.Clock = anim.NewClock(time.Now())
+	}
+	if ths.screens == nil {
+		ths.screens = newScreenGroup()
+	}
+
+	screen.mu.Lock()
+	// Задний фон, назначенный напрямую, может не совпадать с размером экрана
+	screen.fitBackground()
 	screen.BackgroundRefill = true
 
+	// Экран входит в группу экранов этого Sgui
+	if screen.group != ths.screens {
+		for _, o := range screen.Objects {
+			if screen.group != nil {
+				screen.group.remove(o.Widget)
+			}
+			ths.screens.add(o.Widget)
+		}
+		screen.group = ths.screens
+	}
+
+	// Виджеты экрана работают по часам этого Sgui
+	screen.clock = ths.Clock
+	for _, o := range screen.Objects {
+		screen.bind(o.Widget)
+	}
+	screen.mu.Unlock()
+
 	// Ждем, когда завершится обработка действующего экрана
-	if ths.ActiveScreen != nil {
-		ths.ActiveScreen.mu.Lock()
-		defer ths.ActiveScreen.mu.Unlock()
+	prev := ths.activeScreen()
+	if prev != nil {
+		prev.mu.Lock()
 	}
 
+	ths.mu.Lock()
 	ths.ActiveScreen = screen
+	ths.mu.Unlock()
+
+	if prev != nil {
+		prev.mu.Unlock()
+	}
 
-	// Запускаем функцию, которая отрабатывает при ключении этого экрана
+	// Запускаем функцию, которая отрабатывает при ключении этого экрана.
+	// Экран уже не заблокирован, из нее можно добавлять виджеты
 	if screen.RunOnce != nil {
 		screen.RunOnce()
 	}
 
 }
 
+// Возвращает активный экран
+func (ths *Sgui) activeScreen() *Screen {
+	ths.mu.Lock()
+	defer ths.mu.Unlock()
+	return ths.ActiveScreen
+}
+
 // Устанавливает оверлей
 func (ths *Sgui) SetOverlay(overlay *Overlay) {
 	// Ждем, когда завершится обработка действующего экрана
@@ -81,16 +151,52 @@ func (ths *Sgui) StartInputEventHandler() {
 
 // Обрабатывает соыбытие ввода
 func (ths *Sgui) Event(event IEvent) {
+	screen := ths.activeScreen()
+
+	// Запись событий с экраном, активным в момент события
+	if ths.Recorder != nil {
+		id := ""
+		if screen != nil {
+			id = screen.ID
+		}
+		ths.Recorder.record(event, id)
+	}
+
+	if screen == nil {
+		return
+	}
+
+	// Копия списка объектов: обработчики вызываются без блокировки
+	// и могут сами изменять экран
+	screen.mu.Lock()
+	hook := screen.TapHooker
+	objects := slices.Clone(screen.Objects)
+	screen.mu.Unlock()
+
 	// Передача нажатия в хук экрана
-	if ths.ActiveScreen.TapHooker != nil {
+	if hook != nil {
 		switch event.(type) {
 		case EventTap:
-			ths.ActiveScreen.TapHooker(event.Position())
+			hook(event.Position())
 		}
 	}
 
-	// Поиск виджетов в зоне нажатия и передача ему события
-	for _, o := range ths.ActiveScreen.Objects {
+	// Поиск виджетов в зоне нажатия и передача им события
+	ths.objectsEvent(objects, event)
+
+	// Виджеты оверлея получают события так же, как виджеты экрана
+	if overlay := ths.Overlay; overlay != nil {
+		overlay.mu.Lock()
+		objects := slices.Clone(overlay.Objects)
+		overlay.mu.Unlock()
+
+		ths.objectsEvent(objects, event)
+	}
+}
+
+// Передает событие объектам, в зоне которых оно произошло
+func (ths *Sgui) objectsEvent(objects []Object, event IEvent) {
+	for _, o := range objects {
 		// если виджет отключен или скрыт, не передаем ему событие
 		if o.Widget.Disabled() || o.Widget.Hidden() {
 			continue
@@ -108,74 +214,196 @@ func (ths *Sgui) Event(event IEvent) {
 		switch event.(type) {
 		case EventTap:
 			if event.Position().In(wpos) {
-				go o.Widget.Tap(event.Position())
+				ths.dispatch(o.Widget.Tap, event.Position())
 			}
 		case EventRelease:
-			go o.Widget.Release(event.Position())
+			ths.dispatch(o.Widget.Release, event.Position())
 		}
 	}
+}
 
+// Вызывает обработчик события виджета
+func (ths *Sgui) dispatch(handler func(image.Point), pos image.Point) {
+	if ths.SyncEvents {
+		handler(pos)
+		return
+	}
+	go handler(pos)
 }
 
-// Отрисовывает объекты на дисплей
+// Отрисовывает объекты на дисплей.
+// Перерисовываются только области (damage), где изображение изменилось:
+// в области заново рисуется фон экрана, затем все пересекающие ее объекты
+// экрана и оверлея снизу вверх, с учетом прозрачности
 func (ths *Sgui) Render() {
+	// Начинаем новый кадр: срабатывают таймеры и анимации.
+	// Выполняется до блокировки экрана, что бы из таймеров
+	// можно было изменять экран
+	now := time.Now
+	if ths.TimeSource != nil {
+		now = ths.TimeSource
+	}
+	if ths.Clock == nil {
+		ths.Clock = anim.NewClock(now())
+	}
+	ths.Clock.Tick(now())
+
 	// Проверяем, установлен ли экран
-	if ths.ActiveScreen == nil {
+	screen := ths.activeScreen()
+	if screen == nil {
 		return
 	}
-	ths.ActiveScreen.mu.Lock()
-	defer ths.ActiveScreen.mu.Unlock()
+	screen.mu.Lock()
+	defer screen.mu.Unlock()
 
-	// Сначала рисуем background
-	if ths.ActiveScreen.Background != nil && ths.ActiveScreen.BackgroundRefill {
-		copy(ths.Display.Pix, ths.ActiveScreen.Background.Pix)
+	// Все объекты экрана и оверлея в порядке отрисовки
+	layers := make([]*Object, 0, len(screen.Objects))
+	for i := range screen.Objects {
+		layers = append(layers, &screen.Objects[i])
 	}
 
-	// Отрисовка на дисплей объектов с экрана, в порядке их добавления
-	for _, o := range ths.ActiveScreen.Objects {
-		ths.DrawObject(&o)
+	if ths.Overlay != nil {
+		ths.Overlay.mu.Lock()
+		defer ths.Overlay.mu.Unlock()
+
+		for i := range ths.Overlay.Objects {
+			layers = append(layers, &ths.Overlay.Objects[i])
+		}
 	}
 
-	// Отрисовываем оверлей
-	if ths.Overlay == nil {
-		return
+	// Собираем области, которые нужно перерисовать
+	damage := screen.damage
+	screen.damage = nil
+
+	if screen.BackgroundRefill {
+		damage = []image.Rectangle{ths.Display.Bounds()}
+		screen.BackgroundRefill = false
 	}
-	ths.Overlay.mu.Lock()
-	defer ths.Overlay.mu.Unlock()
 
-	// Отрисовка на дисплей объектов с экрана, в порядке их добавления
-	for _, o := range ths.Overlay.Objects {
-		ths.DrawObject(&o)
+	// Текущие области объектов. У скрытых объектов область пустая
+	rects := make([]image.Rectangle, len(layers))
+
+	for i, o := range layers {
+		// Таймеры виджета идут по часам этого Sgui
+		if w, ok := o.Widget.(ITimedWidget); ok {
+			w.SetClock(ths.Clock)
+		}
+
+		// Обновление внутреннего состояния виджета
+		o.Widget.Update()
+
+		if !o.Widget.Hidden() {
+			rects[i] = o.rect()
+		}
+
+		// Объект скрыт, показан, перемещен или изменил размер:
+		// перерисовываем и старую, и новую область
+		if rects[i] != o.drawn {
+			damage = addDamage(damage, o.drawn)
+			damage = addDamage(damage, rects[i])
+			continue
+		}
+
+		// Если изображение виджета не менялось,
+		// то и перерисовывать его не нужно
+		if o.Widget.Updated() {
+			damage = addDamage(damage, rects[i])
+		}
 	}
 
-	if ths.ActiveScreen.BackgroundRefill {
-		ths.ActiveScreen.BackgroundRefill = false
+	// Рендеры виджетов, полученные в этом кадре
+	renders := make([]*image.RGBA, len(layers))
+
+	for _, r := range damage {
+		r = r.Intersect(ths.Display.Bounds())
+		if r.Empty() {
+			continue
+		}
+
+		// Фон экрана
+		if screen.Background != nil {
+			draw.Draw(ths.Display, r, screen.Background, r.Min, draw.Src)
+		} else {
+			draw.Draw(ths.Display, r, image.Transparent, image.Point{}, draw.Src)
+		}
+
+		// Видимые объекты, пересекающие область, снизу вверх
+		for i, o := range layers {
+			if !r.Overlaps(rects[i]) {
+				continue
+			}
+
+			if renders[i] == nil {
+				renders[i] = o.Widget.Render()
+				if renders[i] == nil {
+					log.Println("SGUI: Widget render error - no render")
+					continue
+				}
+			}
+
+			ths.drawObject(o, renders[i], r)
+		}
 	}
 
+	for i, o := range layers {
+		o.drawn = rects[i]
+	}
 }
 
+// Обновляет объект и, если его изображение изменилось или экран
+// перерисовывается целиком, рисует его на дисплей поверх текущего изображения.
+//
+// Deprecated: объекты экрана и оверлея отрисовывает Render(),
+// перерисовывая только измененные области
 func (ths *Sgui) DrawObject(o *Object) {
-	// Обновление внутреннего состояния виджета
 	o.Widget.Update()
 
-	// Если изображение виджета не менялось,
-	// то и перерисовывать его не нужно. Пропускаем этот виджет
-	// Если была отрисовка бэкграунда, то виджет нужно снова отрисовать
-	if !o.Widget.Updated() && !ths.ActiveScreen.BackgroundRefill {
+	screen := ths.activeScreen()
+	refill := screen != nil && screen.BackgroundRefill
+	if !o.Widget.Updated() && !refill {
 		return
 	}
 
 	wr := o.Widget.Render()
-
 	if wr == nil {
 		log.Println("SGUI: Widget render error - no render")
 		return
 	}
 
-	draw.Draw(
-		ths.Display,
-		ths.Display.Bounds(),
-		wr,
-		image.Point{-o.Position.X, -o.Position.Y},
-		draw.Src)
+	ths.drawObject(o, wr, ths.Display.Bounds())
+}
+
+// Рисует рендер объекта на дисплей в пределах области clip
+func (ths *Sgui) drawObject(o *Object, wr *image.RGBA, clip image.Rectangle) {
+	dst := wr.Bounds().Sub(wr.Bounds().Min).Add(o.Position).Intersect(clip)
+	if dst.Empty() {
+		return
+	}
+	src := dst.Min.Sub(o.Position).Add(wr.Bounds().Min)
+
+	if o.transparency <= 0 {
+		draw.Draw(ths.Display, dst, wr, src, draw.Over)
+		return
+	}
+
+	mask := image.NewUniform(color.Alpha{A: uint8(math.Round(255 * o.Opacity()))})
+	draw.DrawMask(ths.Display, dst, wr, src, mask, image.Point{}, draw.Over)
+}
+
+// Добавляет область в список перерисовки.
+// Пересекающиеся области объединяются
+func addDamage(damage []image.Rectangle, r image.Rectangle) []image.Rectangle {
+	if r.Empty() {
+		return damage
+	}
+
+	for i := 0; i < len(damage); i++ {
+		if damage[i].Overlaps(r) {
+			r = r.Union(damage[i])
+			damage = append(damage[:i], damage[i+1:]...)
+			i = -1 // Объединенная область может пересекать уже проверенные
+		}
+	}
+
+	return append(damage, r)
 }
@@ -0,0 +1,289 @@
+package sgui
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+	"testing"
+	"time"
+
+	"github.com/anatolypaw/sgui/anim"
+)
+
+// Виджет, залитый одним цветом
+type colorWidget struct {
+	stubWidget
+	c       color.Color
+	updated bool
+}
+
+func (w *colorWidget) Render() *image.RGBA {
+	img := image.NewRGBA(image.Rectangle{Max: w.size})
+	draw.Draw(img, img.Bounds(), image.NewUniform(w.c), image.Point{}, draw.Src)
+	w.updated = false
+	return img
+}
+
+func (w *colorWidget) Updated() bool { return w.updated }
+
+func TestRenderCompositing(t *testing.T) {
+	display := image.NewRGBA(image.Rect(0, 0, 20, 20))
+	gui, _ := New(display, nil)
+
+	screen := NewScreen(display.Bounds())
+	screen.SetBackground(color.White)
+
+	bottom := &colorWidget{stubWidget: stubWidget{size: image.Point{10, 20}}, c: color.RGBA{255, 0, 0, 255}}
+	top := &colorWidget{stubWidget: stubWidget{size: image.Point{20, 10}}, c: color.RGBA{0, 0, 128, 128}}
+	screen.AddWidget(0, 0, bottom)
+	screen.AddWidget(0, 5, top)
+	gui.SetScreen(&screen)
+
+	gui.Render()
+	overRed := display.RGBAAt(5, 8)
+	overWhite := display.RGBAAt(15, 8)
+	if overRed != (color.RGBA{127, 0, 128, 255}) || overWhite != (color.RGBA{127, 127, 255, 255}) {
+		t.Fatalf("translucent widget: over red %v, over white %v", overRed, overWhite)
+	}
+
+	// Полупрозрачный виджет перерисовывается несколько раз,
+	// но не накладывается сам на себя
+	for i := 0; i < 3; i++ {
+		top.updated = true
+		gui.Render()
+	}
+	if got := display.RGBAAt(5, 8); got != overRed {
+		t.Fatalf("after redraws: %v, want %v", got, overRed)
+	}
+
+	// Изменение нижнего виджета не стирает верхний
+	bottom.c = color.RGBA{0, 255, 0, 255}
+	bottom.updated = true
+	gui.Render()
+	if got := display.RGBAAt(5, 8); got != (color.RGBA{0, 127, 128, 255}) {
+		t.Fatalf("after lower widget change: %v", got)
+	}
+
+	screen.SetOpacity(top, 0)
+	gui.Render()
+	if got := display.RGBAAt(5, 8); got != (color.RGBA{0, 255, 0, 255}) {
+		t.Fatalf("invisible widget: %v", got)
+	}
+	if got := display.RGBAAt(15, 15); got != (color.RGBA{255, 255, 255, 255}) {
+		t.Fatalf("background: %v", got)
+	}
+}
+
+func TestRenderHideAndMove(t *testing.T) {
+	display := image.NewRGBA(image.Rect(0, 0, 20, 20))
+	gui, _ := New(display, nil)
+
+	screen := NewScreen(display.Bounds())
+	screen.SetBackground(color.White)
+
+	red := color.RGBA{255, 0, 0, 255}
+	blue := color.RGBA{0, 0, 255, 255}
+	white := color.RGBA{255, 255, 255, 255}
+
+	bottom := &colorWidget{stubWidget: stubWidget{size: image.Point{10, 10}}, c: red}
+	top := &colorWidget{stubWidget: stubWidget{size: image.Point{10, 10}}, c: blue}
+	screen.AddWidget(0, 0, bottom)
+	screen.AddWidget(5, 5, top)
+	gui.SetScreen(&screen)
+	gui.Render()
+
+	if got := display.RGBAAt(7, 7); got != blue {
+		t.Fatalf("top widget: %v", got)
+	}
+
+	// Под скрытым виджетом восстанавливается нижний виджет и фон
+	top.Hide()
+	gui.Render()
+	if got := display.RGBAAt(7, 7); got != red {
+		t.Fatalf("under hidden widget: %v, want red", got)
+	}
+	if got := display.RGBAAt(12, 12); got != white {
+		t.Fatalf("background under hidden widget: %v", got)
+	}
+
+	top.Show()
+	screen.SetPosition(top, image.Point{10, 10})
+	gui.Render()
+	if got := display.RGBAAt(7, 7); got != red {
+		t.Fatalf("old position of moved widget: %v, want red", got)
+	}
+	if got := display.RGBAAt(12, 12); got != blue {
+		t.Fatalf("new position of moved widget: %v, want blue", got)
+	}
+
+	screen.RemoveWidget(bottom)
+	gui.Render()
+	if got := display.RGBAAt(2, 2); got != white {
+		t.Fatalf("removed widget: %v, want white", got)
+	}
+}
+
+func TestEventOverlay(t *testing.T) {
+	display := image.NewRGBA(image.Rect(0, 0, 20, 20))
+	gui, _ := New(display, nil)
+	gui.SyncEvents = true
+
+	screen := NewScreen(display.Bounds())
+	gui.SetScreen(&screen)
+
+	overlay := NewOverlay(display.Bounds())
+	gui.SetOverlay(&overlay)
+
+	enabled := &tapCounter{stubWidget: stubWidget{size: image.Point{10, 10}}}
+	disabled := &tapCounter{stubWidget: stubWidget{size: image.Point{10, 10}}}
+	disabled.Disable()
+	overlay.AddWidget(0, 0, enabled)
+	overlay.AddWidget(0, 0, disabled)
+
+	gui.Event(EventTap{Pos: image.Point{5, 5}})
+	gui.Event(EventRelease{Pos: image.Point{5, 5}})
+
+	if enabled.taps != 1 || enabled.releases != 1 {
+		t.Errorf("overlay widget: taps=%d releases=%d, want 1 1", enabled.taps, enabled.releases)
+	}
+	if disabled.taps != 0 || disabled.releases != 0 {
+		t.Errorf("disabled overlay widget: taps=%d releases=%d, want 0 0", disabled.taps, disabled.releases)
+	}
+}
+
+// Виджет, удаляющий себя с экрана при нажатии
+type removeOnTap struct {
+	stubWidget
+	screen  *Screen
+	removed chan struct{}
+}
+
+func (w *removeOnTap) Tap(image.Point) {
+	w.screen.RemoveWidget(w)
+	w.removed <- struct{}{}
+}
+
+func TestScreenChangeFromHandlers(t *testing.T) {
+	display := image.NewRGBA(image.Rect(0, 0, 20, 20))
+	gui, _ := New(display, nil)
+
+	screen := NewScreen(display.Bounds())
+	removed := make(chan struct{}, 2)
+	screen.RunOnce = func() {
+		screen.AddWidget(0, 0, &removeOnTap{
+			stubWidget: stubWidget{size: image.Point{10, 10}},
+			screen:     &screen,
+			removed:    removed,
+		})
+	}
+
+	done := make(chan struct{})
+	go func() {
+		// Повторная установка активного экрана
+		gui.SetScreen(&screen)
+		gui.SetScreen(&screen)
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("deadlock")
+	}
+	if len(screen.Objects) != 2 {
+		t.Fatalf("objects after RunOnce %d, want 2", len(screen.Objects))
+	}
+
+	// Обработчики изменяют экран во время передачи события
+	gui.Event(EventTap{Pos: image.Point{5, 5}})
+	for i := 0; i < 2; i++ {
+		select {
+		case <-removed:
+		case <-time.After(time.Second):
+			t.Fatal("Tap is not called")
+		}
+	}
+
+	// Оба добавленных виджета получили нажатие и удалили себя
+	if len(screen.Objects) != 0 {
+		t.Errorf("objects %d, want 0", len(screen.Objects))
+	}
+}
+
+// Виджет с таймером
+type timedWidget struct {
+	stubWidget
+	timers anim.Timers
+}
+
+func (w *timedWidget) SetClock(c *anim.Clock) { w.timers.SetClock(c) }
+
+func TestWidgetClock(t *testing.T) {
+	start := time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC)
+	newGui := func() (*Sgui, *Screen, *time.Time) {
+		display := image.NewRGBA(image.Rect(0, 0, 20, 20))
+		gui, _ := New(display, nil)
+		now := start
+		gui.Clock = anim.NewClock(now)
+		gui.TimeSource = func() time.Time { return now }
+		screen := NewScreen(display.Bounds())
+		gui.SetScreen(&screen)
+		return &gui, &screen, &now
+	}
+
+	// У каждого Sgui свои часы
+	a, screenA, nowA := newGui()
+	b, screenB, _ := newGui()
+
+	wa := &timedWidget{stubWidget: stubWidget{size: image.Point{10, 10}}}
+	wb := &timedWidget{stubWidget: stubWidget{size: image.Point{10, 10}}}
+	screenA.AddWidget(0, 0, wa)
+	screenB.AddWidget(0, 0, wb)
+
+	firedA, firedB := 0, 0
+	wa.timers.Every(100*time.Millisecond, func() { firedA++ })
+	wb.timers.Every(100*time.Millisecond, func() { firedB++ })
+
+	*nowA = start.Add(250 * time.Millisecond)
+	a.Render()
+	b.Render()
+	if firedA != 1 || firedB != 0 {
+		t.Fatalf("fired a=%d b=%d, want 1 0", firedA, firedB)
+	}
+
+	// Виджет, оставшийся на другом экране того же Sgui, продолжает работать
+	other := NewScreen(screenA.Size)
+	other.AddWidget(0, 0, wa)
+	a.SetScreen(&other)
+	a.SetScreen(screenA)
+	screenA.RemoveWidget(wa)
+	*nowA = start.Add(350 * time.Millisecond)
+	a.Render()
+	if firedA != 2 {
+		t.Fatalf("widget on other screen: timer fired %d times, want 2", firedA)
+	}
+
+	// Таймеры виджета, удаленного со всех экранов, останавливаются
+	other.RemoveWidget(wa)
+	*nowA = start.Add(time.Second)
+	a.Render()
+	if firedA != 2 {
+		t.Errorf("removed widget timer fired %d times, want 2", firedA)
+	}
+}
+
+func TestDrawObject(t *testing.T) {
+	display := image.NewRGBA(image.Rect(0, 0, 20, 20))
+	gui, _ := New(display, nil)
+
+	red := color.RGBA{255, 0, 0, 255}
+	w := &colorWidget{stubWidget: stubWidget{size: image.Point{10, 10}}, c: red, updated: true}
+	gui.DrawObject(&Object{Widget: w, Position: image.Point{5, 5}})
+
+	if got := display.RGBAAt(7, 7); got != red {
+		t.Errorf("object: %v, want %v", got, red)
+	}
+	if got := display.RGBAAt(2, 2); got != (color.RGBA{}) {
+		t.Errorf("outside object: %v", got)
+	}
+}
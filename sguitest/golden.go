@@ -0,0 +1,145 @@
+package sguitest
+
+import (
+	"flag"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// Флаг для перезаписи эталонов: go test ./... -update
+var update = flag.Bool("update", false, "перезаписать эталонные изображения")
+
+// Каталог с эталонами, относительно каталога тестируемого пакета
+const GoldenDir = "testdata"
+
+// Сравнивает изображение с эталоном testdata/<name>.golden.png.
+// Пиксели считаются совпадающими, если каждый канал отличается
+// не более чем на tolerance.
+// При расхождении рядом с эталоном сохраняются <name>.actual.png
+// и <name>.diff.png, в котором отличающиеся пиксели отмечены красным.
+// С флагом -update эталон перезаписывается.
+func AssertGolden(t testing.TB, name string, img image.Image, tolerance uint8) {
+	t.Helper()
+
+	goldenPath := filepath.Join(GoldenDir, name+".golden.png")
+	actualPath := filepath.Join(GoldenDir, name+".actual.png")
+	diffPath := filepath.Join(GoldenDir, name+".diff.png")
+
+	if *update {
+		if err := writePNG(goldenPath, img); err != nil {
+			t.Fatalf("golden %s: %v", name, err)
+		}
+		os.Remove(actualPath)
+		os.Remove(diffPath)
+		return
+	}
+
+	golden, err := readPNG(goldenPath)
+	if err != nil {
+		t.Fatalf("golden %s: %v (запустите тесты с флагом -update)", name, err)
+	}
+
+	diff, n := Compare(golden, img, tolerance)
+	if n == 0 {
+		os.Remove(actualPath)
+		os.Remove(diffPath)
+		return
+	}
+
+	if err := writePNG(actualPath, img); err != nil {
+		t.Errorf("golden %s: %v", name, err)
+	}
+	if diff != nil {
+		if err := writePNG(diffPath, diff); err != nil {
+			t.Errorf("golden %s: %v", name, err)
+		}
+	}
+
+	if diff == nil {
+		t.Errorf("golden %s: size %v, want %v",
+			name, img.Bounds().Size(), golden.Bounds().Size())
+		return
+	}
+	t.Errorf("golden %s: %d pixels differ (tolerance %d), see %s",
+		name, n, tolerance, diffPath)
+}
+
+// Сравнивает два изображения попиксельно.
+// Возвращает количество отличающихся пикселей и изображение различий.
+// Если размеры не совпадают, то изображение различий nil,
+// а количество равно количеству пикселей в большем изображении
+func Compare(want, got image.Image, tolerance uint8) (*image.RGBA, int) {
+	wb, gb := want.Bounds(), got.Bounds()
+	if wb.Size() != gb.Size() {
+		n := max(wb.Dx()*wb.Dy(), gb.Dx()*gb.Dy(), 1)
+		return nil, n
+	}
+
+	// Основа изображения различий - приглушенное полученное изображение
+	diff := image.NewRGBA(image.Rectangle{Max: wb.Size()})
+	draw.Draw(diff, diff.Bounds(), got, gb.Min, draw.Src)
+	draw.Draw(diff, diff.Bounds(),
+		image.NewUniform(color.RGBA{255, 255, 255, 200}),
+		image.Point{}, draw.Over)
+
+	n := 0
+	for y := 0; y < wb.Dy(); y++ {
+		for x := 0; x < wb.Dx(); x++ {
+			w := color.RGBAModel.Convert(want.At(wb.Min.X+x, wb.Min.Y+y)).(color.RGBA)
+			g := color.RGBAModel.Convert(got.At(gb.Min.X+x, gb.Min.Y+y)).(color.RGBA)
+			if channelDiff(w.R, g.R) > tolerance ||
+				channelDiff(w.G, g.G) > tolerance ||
+				channelDiff(w.B, g.B) > tolerance ||
+				channelDiff(w.A, g.A) > tolerance {
+				diff.SetRGBA(x, y, color.RGBA{255, 0, 0, 255})
+				n++
+			}
+		}
+	}
+
+	return diff, n
+}
+
+func channelDiff(a, b uint8) uint8 {
+	if a > b {
+		return a - b
+	}
+	return b - a
+}
+
+func readPNG(path string) (image.Image, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	return png.Decode(f)
+}
+
+func writePNG(path string, img image.Image) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+
+	if err := png.Encode(f, img); err != nil {
+		f.Close()
+		return err
+	}
+
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("close %s: %w", path, err)
+	}
+	return nil
+}
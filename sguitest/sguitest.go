@@ -0,0 +1,142 @@
+// Средства для тестирования интерфейсов на sgui без реального дисплея.
+// Harness создает Sgui, рисующий в память, позволяет подавать
+// сценарии нажатий, прокручивать кадры и сравнивать результат с эталонами.
+
+package sguitest
+
+import (
+	"image"
+	"testing"
+	"time"
+
+	"github.com/anatolypaw/sgui"
+	"github.com/anatolypaw/sgui/anim"
+)
+
+// Интервал между кадрами по умолчанию
+const DefaultFrameInterval = 20 * time.Millisecond
+
+// Тестовое окружение с дисплеем в памяти
+type Harness struct {
+	T       testing.TB
+	Gui     *sgui.Sgui
+	Display *image.RGBA
+	Frame   int // Количество отрисованных кадров
+
+	// Подставное время. Перед каждым кадром увеличивается на FrameInterval
+	Now           time.Time
+	FrameInterval time.Duration
+}
+
+// Шаг сценария. Сначала передается событие (если указано),
+// затем отрисовывается заданное количество кадров
+type Step struct {
+	Event  sgui.IEvent
+	Frames int
+}
+
+// Создает окружение с дисплеем заданного размера.
+// События передаются виджетам синхронно, время кадров подставное
+// и начинается с фиксированного момента, поэтому анимации воспроизводимы
+func New(t testing.TB, size image.Point) *Harness {
+	t.Helper()
+
+	display := image.NewRGBA(image.Rectangle{Max: size})
+	gui, err := sgui.New(display, nil)
+	if err != nil {
+		t.Fatalf("sgui.New: %v", err)
+	}
+
+	h := &Harness{
+		T:             t,
+		Gui:           &gui,
+		Display:       display,
+		Now:           time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC),
+		FrameInterval: DefaultFrameInterval,
+	}
+
+	gui.Clock = anim.NewClock(h.Now)
+	gui.SyncEvents = true
+	gui.TimeSource = func() time.Time { return h.Now }
+
+	return h
+}
+
+// Создает экран размером с дисплей и делает его активным
+func (h *Harness) NewScreen() *sgui.Screen {
+	screen := sgui.NewScreen(h.Display.Bounds())
+	h.Gui.SetScreen(&screen)
+	return &screen
+}
+
+// Отрисовывает n кадров
+func (h *Harness) Frames(n int) {
+	for i := 0; i < n; i++ {
+		h.Now = h.Now.Add(h.FrameInterval)
+		h.Gui.Render()
+		h.Frame++
+	}
+}
+
+// Отрисовывает кадры, пока не пройдет время d
+func (h *Harness) Advance(d time.Duration) {
+	end := h.Now.Add(d)
+	for h.Now.Before(end) {
+		h.Frames(1)
+	}
+}
+
+// Нажатие в точке
+func (h *Harness) Tap(x, y int) {
+	h.Gui.Event(sgui.EventTap{Pos: image.Point{X: x, Y: y}})
+}
+
+// Отпускание в точке
+func (h *Harness) Release(x, y int) {
+	h.Gui.Event(sgui.EventRelease{Pos: image.Point{X: x, Y: y}})
+}
+
+// Нажатие и отпускание с отрисовкой кадра между ними
+func (h *Harness) Click(x, y int) {
+	h.Tap(x, y)
+	h.Frames(1)
+	h.Release(x, y)
+	h.Frames(1)
+}
+
+// Выполняет сценарий
+func (h *Harness) Run(steps ...Step) {
+	for _, s := range steps {
+		if s.Event != nil {
+			h.Gui.Event(s.Event)
+		}
+		h.Frames(s.Frames)
+	}
+}
+
+// Шаг сценария: нажатие и один кадр
+func TapAt(x, y int) Step {
+	return Step{Event: sgui.EventTap{Pos: image.Point{X: x, Y: y}}, Frames: 1}
+}
+
+// Шаг сценария: отпускание и один кадр
+func ReleaseAt(x, y int) Step {
+	return Step{Event: sgui.EventRelease{Pos: image.Point{X: x, Y: y}}, Frames: 1}
+}
+
+// Шаг сценария: n кадров без событий
+func Wait(frames int) Step {
+	return Step{Frames: frames}
+}
+
+// Сравнивает содержимое дисплея с эталоном name
+func (h *Harness) AssertDisplay(name string, tolerance uint8) {
+	h.T.Helper()
+	AssertGolden(h.T, name, h.Display, tolerance)
+}
+
+// Сравнивает рендер виджета с эталоном name
+func (h *Harness) AssertWidget(name string, w sgui.IWidget, tolerance uint8) {
+	h.T.Helper()
+	AssertGolden(h.T, name, w.Render(), tolerance)
+}
@@ -0,0 +1,67 @@
+package sguitest_test
+
+import (
+	"image"
+	"image/color"
+	"testing"
+	"time"
+
+	"github.com/anatolypaw/sgui/sguitest"
+	"github.com/anatolypaw/sgui/widget"
+)
+
+func TestCompare(t *testing.T) {
+	a := image.NewRGBA(image.Rect(0, 0, 4, 4))
+	b := image.NewRGBA(image.Rect(0, 0, 4, 4))
+	b.SetRGBA(1, 1, color.RGBA{3, 0, 0, 0})
+	b.SetRGBA(2, 2, color.RGBA{10, 0, 0, 0})
+
+	if _, n := sguitest.Compare(a, b, 3); n != 1 {
+		t.Errorf("Compare(tolerance 3) = %d, want 1", n)
+	}
+	if _, n := sguitest.Compare(a, b, 0); n != 2 {
+		t.Errorf("Compare(tolerance 0) = %d, want 2", n)
+	}
+
+	c := image.NewRGBA(image.Rect(0, 0, 2, 2))
+	if diff, n := sguitest.Compare(a, c, 255); diff != nil || n == 0 {
+		t.Errorf("Compare of different sizes = %v, %d", diff, n)
+	}
+}
+
+func TestHarnessButton(t *testing.T) {
+	h := sguitest.New(t, image.Point{120, 60})
+	screen := h.NewScreen()
+	screen.SetBackground(color.White)
+
+	clicked := make(chan struct{}, 1)
+	button := widget.NewButton(&widget.ButtonParam{
+		Size:             image.Point{100, 40},
+		OnClick:          func() { clicked <- struct{}{} },
+		Text:             "OK",
+		TextSize:         20,
+		ReleaseFillColor: color.RGBA{200, 200, 200, 255},
+		PressFillColor:   color.RGBA{150, 150, 150, 255},
+		BackgroundColor:  color.White,
+		CornerRadius:     8,
+		StrokeWidth:      2,
+		StrokeColor:      color.Black,
+		TextColor:        color.Black,
+	}, nil)
+	screen.AddWidget(10, 10, button)
+
+	h.Frames(1)
+	h.AssertDisplay("button_released", 1)
+
+	h.Run(sguitest.TapAt(50, 30))
+	h.AssertDisplay("button_pressed", 1)
+
+	h.Run(sguitest.ReleaseAt(50, 30), sguitest.Wait(10))
+	h.AssertDisplay("button_released", 1)
+
+	select {
+	case <-clicked:
+	case <-time.After(time.Second):
+		t.Fatal("OnClick is not called")
+	}
+}
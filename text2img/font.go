@@ -0,0 +1,288 @@
+package text2img
+
+import (
+	"container/list"
+	"fmt"
+	"image"
+	"os"
+	"sync"
+
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/gofont/gobold"
+	"golang.org/x/image/font/gofont/gomedium"
+	"golang.org/x/image/font/gofont/gomono"
+	"golang.org/x/image/font/gofont/gomonobold"
+	"golang.org/x/image/font/gofont/goregular"
+	"golang.org/x/image/font/opentype"
+	"golang.org/x/image/font/sfnt"
+	"golang.org/x/image/math/fixed"
+)
+
+// Встроенные семейства шрифтов
+const (
+	DefaultFamily = "Go"      // Обычный, средний и жирный
+	MonoFamily    = "Go Mono" // Моноширинный обычный и жирный
+)
+
+// Разрешение по умолчанию: размер шрифта в пунктах равен размеру в пикселях
+const DefaultDPI = 72
+
+// Сколько созданных начертаний хранится одновременно.
+// При переполнении удаляются давно не использованные
+const maxFaces = 64
+
+// Описание шрифта надписи
+type Font struct {
+	Family string      // Семейство, "" - DefaultFamily
+	Weight font.Weight // Насыщенность, по умолчанию обычная
+	Size   float64     // Размер в пунктах
+}
+
+// Начертание семейства. Встроенные шрифты разбираются при первом использовании
+type fontData struct {
+	data   []byte
+	parsed *sfnt.Font
+	err    error
+}
+
+func (d *fontData) font() (*sfnt.Font, error) {
+	if d.parsed == nil && d.err == nil {
+		d.parsed, d.err = opentype.Parse(d.data)
+	}
+	return d.parsed, d.err
+}
+
+type faceKey struct {
+	family string
+	weight font.Weight
+	size   float64
+}
+
+type registry struct {
+	mu        sync.Mutex
+	families  map[string]map[font.Weight]*fontData
+	fallbacks map[string][]string
+	dpi       float64
+	hinting   font.Hinting
+
+	// Созданные начертания нужного размера.
+	// faceOrder - от недавно использованных к давно, элементы *cachedFace
+	faces     map[faceKey]*list.Element
+	faceOrder *list.List
+
+	// Номер изменения реестра. Входит в ключ кэша рендеров,
+	// чтобы после замены шрифтов надписи рисовались заново
+	version uint64
+}
+
+var fonts = newRegistry()
+
+func newRegistry() *registry {
+	r := &registry{
+		families:  make(map[string]map[font.Weight]*fontData),
+		fallbacks: make(map[string][]string),
+		dpi:       DefaultDPI,
+		hinting:   font.HintingNone,
+	}
+	r.changed()
+	r.add(DefaultFamily, font.WeightNormal, &fontData{data: goregular.TTF})
+	r.add(DefaultFamily, font.WeightMedium, &fontData{data: gomedium.TTF})
+	r.add(DefaultFamily, font.WeightBold, &fontData{data: gobold.TTF})
+	r.add(MonoFamily, font.WeightNormal, &fontData{data: gomono.TTF})
+	r.add(MonoFamily, font.WeightBold, &fontData{data: gomonobold.TTF})
+	return r
+}
+
+// Регистрирует начертание weight семейства family из данных TTF или OTF.
+// Начертание с тем же семейством и насыщенностью заменяется
+func Register(family string, weight font.Weight, data []byte) error {
+	f, err := opentype.Parse(data)
+	if err != nil {
+		return fmt.Errorf("text2img: font %q: %w", family, err)
+	}
+
+	fonts.mu.Lock()
+	defer fonts.mu.Unlock()
+	fonts.add(family, weight, &fontData{data: data, parsed: f})
+	return nil
+}
+
+// Регистрирует начертание из файла TTF или OTF
+func RegisterFile(family string, weight font.Weight, path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("text2img: font %q: %w", family, err)
+	}
+	return Register(family, weight, data)
+}
+
+// Устанавливает семейства, из которых берутся символы,
+// отсутствующие в семействе family (кириллица, иероглифы, знаки).
+// Семейства перебираются по порядку, последним всегда идет DefaultFamily
+func SetFallback(family string, fallbacks ...string) {
+	fonts.mu.Lock()
+	defer fonts.mu.Unlock()
+	fonts.fallbacks[family] = append([]string(nil), fallbacks...)
+	fonts.changed()
+}
+
+// Устанавливает разрешение экрана, DefaultDPI по умолчанию
+func SetDPI(dpi float64) {
+	fonts.mu.Lock()
+	defer fonts.mu.Unlock()
+	if dpi <= 0 {
+		dpi = DefaultDPI
+	}
+	fonts.dpi = dpi
+	fonts.changed()
+}
+
+// Устанавливает выравнивание контуров символов по пикселям,
+// font.HintingNone по умолчанию
+func SetHinting(h font.Hinting) {
+	fonts.mu.Lock()
+	defer fonts.mu.Unlock()
+	fonts.hinting = h
+	fonts.changed()
+}
+
+func (r *registry) add(family string, weight font.Weight, d *fontData) {
+	if r.families[family] == nil {
+		r.families[family] = make(map[font.Weight]*fontData)
+	}
+	r.families[family][weight] = d
+	r.changed()
+}
+
+// Сбрасывает созданные начертания после изменения реестра
+func (r *registry) changed() {
+	r.faces = make(map[faceKey]*list.Element)
+	r.faceOrder = list.New()
+	r.version++
+}
+
+// Возвращает шрифт семейства family с ближайшей к weight насыщенностью
+func (r *registry) lookup(family string, weight font.Weight) *sfnt.Font {
+	var best *fontData
+	bestDist := 0
+	for w, d := range r.families[family] {
+		dist := int(w - weight)
+		if dist < 0 {
+			// При равном расстоянии предпочитается более жирное
+			dist = -dist*2 + 1
+		} else {
+			dist *= 2
+		}
+		if best == nil || dist < bestDist {
+			best, bestDist = d, dist
+		}
+	}
+	if best == nil {
+		return nil
+	}
+	f, err := best.font()
+	if err != nil {
+		return nil
+	}
+	return f
+}
+
+// Возвращает начертание шрифта f с цепочкой замены символов.
+// Вызывается под r.mu
+func (r *registry) face(f Font) (*chainFace, error) {
+	if f.Family == "" {
+		f.Family = DefaultFamily
+	}
+	key := faceKey{f.Family, f.Weight, f.Size}
+	if e, ok := r.faces[key]; ok {
+		r.faceOrder.MoveToFront(e)
+		return e.Value.(*cachedFace).face, nil
+	}
+
+	families := append([]string{f.Family}, r.fallbacks[f.Family]...)
+	families = append(families, DefaultFamily)
+
+	face := &chainFace{}
+	seen := make(map[*sfnt.Font]bool)
+	for _, family := range families {
+		fnt := r.lookup(family, f.Weight)
+		if fnt == nil || seen[fnt] {
+			continue
+		}
+		seen[fnt] = true
+
+		ff, err := opentype.NewFace(fnt, &opentype.FaceOptions{
+			Size:    f.Size,
+			DPI:     r.dpi,
+			Hinting: r.hinting,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("text2img: font %q: %w", family, err)
+		}
+		face.fonts = append(face.fonts, fnt)
+		face.faces = append(face.faces, ff)
+	}
+	if len(face.faces) == 0 {
+		return nil, fmt.Errorf("text2img: no font for family %q", f.Family)
+	}
+
+	r.faces[key] = r.faceOrder.PushFront(&cachedFace{key, face})
+	for r.faceOrder.Len() > maxFaces {
+		old := r.faceOrder.Remove(r.faceOrder.Back()).(*cachedFace)
+		delete(r.faces, old.key)
+	}
+	return face, nil
+}
+
+type cachedFace struct {
+	key  faceKey
+	face *chainFace
+}
+
+// Начертание, берущее отсутствующие символы из следующих шрифтов цепочки.
+// Метрики строки берутся из первого шрифта
+type chainFace struct {
+	fonts []*sfnt.Font
+	faces []font.Face
+	buf   sfnt.Buffer
+}
+
+// Возвращает начертание, в котором есть символ r.
+// Если символа нет ни в одном, то первое
+func (c *chainFace) pick(r rune) font.Face {
+	for i, f := range c.fonts {
+		if g, err := f.GlyphIndex(&c.buf, r); err == nil && g != 0 {
+			return c.faces[i]
+		}
+	}
+	return c.faces[0]
+}
+
+func (c *chainFace) Close() error {
+	return nil
+}
+
+func (c *chainFace) Glyph(dot fixed.Point26_6, r rune) (
+	dr image.Rectangle, mask image.Image, maskp image.Point, advance fixed.Int26_6, ok bool) {
+	return c.pick(r).Glyph(dot, r)
+}
+
+func (c *chainFace) GlyphBounds(r rune) (bounds fixed.Rectangle26_6, advance fixed.Int26_6, ok bool) {
+	return c.pick(r).GlyphBounds(r)
+}
+
+func (c *chainFace) GlyphAdvance(r rune) (advance fixed.Int26_6, ok bool) {
+	return c.pick(r).GlyphAdvance(r)
+}
+
+func (c *chainFace) Kern(r0, r1 rune) fixed.Int26_6 {
+	f := c.pick(r0)
+	if f != c.pick(r1) {
+		return 0
+	}
+	return f.Kern(r0, r1)
+}
+
+func (c *chainFace) Metrics() font.Metrics {
+	return c.faces[0].Metrics()
+}
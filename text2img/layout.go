@@ -0,0 +1,602 @@
+package text2img
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+	"log/slog"
+	"math"
+	"strings"
+	"unicode"
+
+	"github.com/anatolypaw/sgui/rendercache"
+	"golang.org/x/image/font"
+	"golang.org/x/image/math/fixed"
+)
+
+// Выравнивание строк по горизонтали
+type Align int
+
+const (
+	AlignCenter  Align = iota // По середине
+	AlignLeft                 // По левому краю
+	AlignRight                // По правому краю
+	AlignJustify              // По ширине, последняя строка абзаца по левому краю
+)
+
+// Выравнивание текста по вертикали
+type VAlign int
+
+const (
+	VAlignMiddle   VAlign = iota // По середине
+	VAlignTop                    // По верхнему краю
+	VAlignBaseline               // Базовая линия первой строки на Layout.Baseline
+	VAlignBottom                 // По нижнему краю
+)
+
+// Что делать с текстом, который не помещается в область
+type Overflow int
+
+const (
+	OverflowVisible  Overflow = iota // Лишнее обрезается краем изображения
+	OverflowEllipsis                 // Лишние строки убираются, обрезанные строки заканчиваются "…"
+	OverflowShrink                   // Размер шрифта уменьшается до MinSize, пока текст не поместится
+)
+
+// Расположение текста в области.
+// Нулевое значение - одна строка по середине, как у Text2img
+type Layout struct {
+	Wrap   bool // Переносить строки по словам по ширине области
+	Align  Align
+	VAlign VAlign
+
+	// Положение базовой линии первой строки от верхнего края для VAlignBaseline.
+	// Если 0, то строка прижимается к верхнему краю
+	Baseline int
+
+	// Множитель расстояния между строками, 0 - 1
+	LineSpacing float64
+
+	Overflow Overflow
+
+	// Наименьший размер шрифта для OverflowShrink, 0 - половина исходного
+	MinSize float64
+}
+
+// Оформление символов размеченного текста
+type style struct {
+	face    font.Face
+	src     image.Image // Цвет текста
+	back    color.Color // Фон, nil - без фона
+	metrics font.Metrics
+}
+
+// Символ с номером оформления
+type glyph struct {
+	r     rune
+	style int
+}
+
+// Отрезок строки из символов одного оформления
+type fragment struct {
+	text  string
+	style int
+	width fixed.Int26_6
+}
+
+// Строка размеченного текста
+type textLine struct {
+	glyphs []glyph
+	words  [][]glyph // Слова для выравнивания по ширине
+	style  int       // Оформление пустой строки
+
+	width   fixed.Int26_6
+	ascent  fixed.Int26_6
+	descent fixed.Int26_6
+	height  fixed.Int26_6 // Рекомендуемое расстояние между строками
+	last    bool          // Последняя строка абзаца, не растягивается по ширине
+}
+
+// Размеченный текст
+type block struct {
+	styles  []style
+	lines   []textLine
+	spacing float64       // Множитель расстояния между строками
+	width   fixed.Int26_6 // Ширина самой длинной строки
+}
+
+// Расстояние между базовыми линиями строк i-1 и i
+func (b *block) step(i int) fixed.Int26_6 {
+	h := max(b.lines[i-1].height, b.lines[i].height)
+	return fixed.Int26_6(float64(h) * b.spacing)
+}
+
+// Высота текста от верха первой строки до низа последней
+func (b *block) height() fixed.Int26_6 {
+	return b.linesHeight(len(b.lines))
+}
+
+// Высота первых n строк
+func (b *block) linesHeight(n int) fixed.Int26_6 {
+	if n == 0 {
+		return 0
+	}
+	h := b.lines[0].ascent + b.lines[n-1].descent
+	for i := 1; i < n; i++ {
+		h += b.step(i)
+	}
+	return h
+}
+
+// Возвращает true, если текст помещается в область size.
+// 0 по оси - без ограничения
+func (b *block) fits(size image.Point) bool {
+	return (size.X <= 0 || b.width <= fixed.I(size.X)) &&
+		(size.Y <= 0 || b.height() <= fixed.I(size.Y))
+}
+
+// Ключ размеченной надписи в кэше рендеров
+type layoutKey struct {
+	spans   string // spansKey() фрагментов
+	font    Font
+	color   color.RGBA
+	size    image.Point
+	layout  Layout
+	version uint64
+}
+
+// Рисует текст шрифтом f в области размера size по правилам l.
+// Если размер по оси 0, то область по этой оси подгоняется под текст.
+// Рендеры кэшируются, возвращается копия
+func DrawLayout(text string, f Font, c color.Color, size image.Point, l Layout) *image.RGBA {
+	return DrawSpans([]Span{{Text: text}}, f, c, size, l)
+}
+
+// Возвращает размер текста, размеченного в области size по правилам l:
+// ширину самой длинной строки и высоту всех строк
+func MeasureLayout(text string, f Font, size image.Point, l Layout) image.Point {
+	return MeasureSpans([]Span{{Text: text}}, f, size, l)
+}
+
+// Рисует фрагменты текста в области размера size по правилам l.
+// Шрифт f и цвет c используются для полей, не заданных во фрагментах
+func DrawSpans(spans []Span, f Font, c color.Color, size image.Point, l Layout) *image.RGBA {
+	if c == nil {
+		c = color.White
+	}
+	if f.Family == "" {
+		f.Family = DefaultFamily
+	}
+
+	fonts.mu.Lock()
+	defer fonts.mu.Unlock()
+
+	key := layoutKey{spansKey(spans), f, color.RGBAModel.Convert(c).(color.RGBA), size, l, fonts.version}
+	return rendercache.GetCopy(key, func() *image.RGBA {
+		b, err := fonts.layout(spans, f, key.color, size, l)
+		if err != nil {
+			slog.Error("text2img", "err", err)
+			return image.NewRGBA(image.Rectangle{Max: image.Point{max(0, size.X), max(0, size.Y)}})
+		}
+		return b.draw(size, l)
+	})
+}
+
+// Возвращает размер фрагментов текста, размеченных в области size по правилам l
+func MeasureSpans(spans []Span, f Font, size image.Point, l Layout) image.Point {
+	if f.Family == "" {
+		f.Family = DefaultFamily
+	}
+
+	fonts.mu.Lock()
+	defer fonts.mu.Unlock()
+
+	b, err := fonts.layout(spans, f, color.White, size, l)
+	if err != nil {
+		return image.Point{}
+	}
+	return image.Point{b.width.Ceil(), b.height().Ceil()}
+}
+
+// Размечает текст. Вызывается под r.mu
+func (r *registry) layout(spans []Span, f Font, c color.Color, size image.Point, l Layout) (*block, error) {
+	// Размеры фрагментов уменьшаются пропорционально размеру надписи
+	build := func(size0 float64) (*block, error) {
+		styles, text, err := r.styles(spans, f, c, size0/f.Size)
+		if err != nil {
+			return nil, err
+		}
+		return breakLines(styles, text, size.X, l), nil
+	}
+
+	b, err := build(f.Size)
+	if err != nil {
+		return nil, err
+	}
+	if l.Overflow == OverflowEllipsis {
+		b.ellipsize(size)
+	}
+	if l.Overflow != OverflowShrink || f.Size <= 0 || b.fits(size) {
+		return b, nil
+	}
+
+	minSize := l.MinSize
+	if minSize <= 0 {
+		minSize = f.Size / 2
+	}
+
+	// Размер уменьшается с шагом 1pt до minSize. Наибольший подходящий
+	// размер ищется делением пополам, а не перебором всех шагов
+	steps := int(math.Ceil(f.Size - minSize))
+	if steps < 1 {
+		return b, nil
+	}
+	sizeAt := func(step int) float64 {
+		return max(minSize, f.Size-float64(step))
+	}
+
+	lo, hi := 1, steps
+	var fit *block
+	fitStep := 0
+	for lo < hi {
+		mid := (lo + hi) / 2
+		b, err := build(sizeAt(mid))
+		if err != nil {
+			return nil, err
+		}
+		if b.fits(size) {
+			hi, fit, fitStep = mid, b, mid
+		} else {
+			lo = mid + 1
+		}
+	}
+	if fit != nil && fitStep == lo {
+		return fit, nil
+	}
+	return build(sizeAt(lo))
+}
+
+// Создает оформления фрагментов и текст из символов с оформлением
+func (r *registry) styles(spans []Span, f Font, c color.Color, scale float64) ([]style, []glyph, error) {
+	var styles []style
+	var text []glyph
+	for _, span := range spans {
+		sf := span.font(f)
+		sf.Size *= scale
+		face, err := r.face(sf)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		col := span.Color
+		if col == nil {
+			col = c
+		}
+		styles = append(styles, style{
+			face:    face,
+			src:     image.NewUniform(col),
+			back:    span.Background,
+			metrics: face.Metrics(),
+		})
+		for _, ch := range span.Text {
+			text = append(text, glyph{ch, len(styles) - 1})
+		}
+	}
+
+	// Пустой текст рисуется шрифтом надписи
+	if len(styles) == 0 {
+		face, err := r.face(Font{Family: f.Family, Weight: f.Weight, Size: f.Size * scale})
+		if err != nil {
+			return nil, nil, err
+		}
+		styles = append(styles, style{face: face, src: image.NewUniform(c), metrics: face.Metrics()})
+	}
+	return styles, text, nil
+}
+
+// Разбивает текст на строки по переводам строк
+// и, если включен перенос, по ширине width
+func breakLines(styles []style, text []glyph, width int, l Layout) *block {
+	spacing := l.LineSpacing
+	if spacing <= 0 {
+		spacing = 1
+	}
+	b := &block{styles: styles, spacing: spacing}
+
+	limit := fixed.I(width)
+	for _, para := range paragraphs(text) {
+		// Оформление абзаца для пустой строки
+		style := 0
+		if len(para) > 0 {
+			style = para[0].style
+		} else if len(b.lines) > 0 {
+			style = b.lines[len(b.lines)-1].style
+		}
+
+		words, seps := splitWords(para)
+		if !l.Wrap || width <= 0 || len(words) == 0 {
+			b.add(textLine{glyphs: para, style: style, last: true})
+			continue
+		}
+
+		var cur textLine
+		cur.style = style
+		for i, word := range words {
+			next := cur
+			if len(cur.words) > 0 {
+				next.glyphs = concat(cur.glyphs, []glyph{seps[i-1]}, word)
+			} else {
+				next.glyphs = word
+			}
+			if b.measure(next.glyphs) <= limit {
+				next.words = append(cur.words[:len(cur.words):len(cur.words)], word)
+				cur = next
+				continue
+			}
+			if len(cur.words) > 0 {
+				b.add(cur)
+				cur = textLine{style: style}
+			}
+
+			// Слово длиннее строки разбивается по символам
+			for b.measure(word) > limit {
+				n := b.fitGlyphs(word, limit)
+				b.add(textLine{glyphs: word[:n], words: [][]glyph{word[:n]}, style: style})
+				word = word[n:]
+			}
+			if len(word) > 0 {
+				cur = textLine{glyphs: word, words: [][]glyph{word}, style: style}
+			}
+		}
+		if len(cur.words) > 0 {
+			cur.last = true
+			b.add(cur)
+		} else {
+			b.lines[len(b.lines)-1].last = true
+		}
+	}
+	return b
+}
+
+// Делит текст на абзацы по переводам строк
+func paragraphs(text []glyph) [][]glyph {
+	paras := [][]glyph{nil}
+	for _, g := range text {
+		if g.r == '\n' {
+			paras = append(paras, nil)
+			continue
+		}
+		paras[len(paras)-1] = append(paras[len(paras)-1], g)
+	}
+	return paras
+}
+
+// Делит абзац на слова. Разделитель слов i - пробел
+// с оформлением первого пробельного символа после слова
+func splitWords(para []glyph) (words [][]glyph, seps []glyph) {
+	start := -1
+	for i, g := range para {
+		if !unicode.IsSpace(g.r) {
+			if start < 0 {
+				start = i
+			}
+			continue
+		}
+		if start >= 0 {
+			words = append(words, para[start:i])
+			seps = append(seps, glyph{' ', g.style})
+			start = -1
+		}
+	}
+	if start >= 0 {
+		words = append(words, para[start:])
+		seps = append(seps, glyph{' ', para[len(para)-1].style})
+	}
+	return words, seps
+}
+
+// Склеивает последовательности символов в новый срез
+func concat(parts ...[]glyph) []glyph {
+	var out []glyph
+	for _, p := range parts {
+		out = append(out, p...)
+	}
+	return out
+}
+
+// Делит символы на отрезки одного оформления и измеряет их
+func (b *block) fragments(glyphs []glyph) []fragment {
+	var frags []fragment
+	var sb strings.Builder
+	for i, g := range glyphs {
+		sb.WriteRune(g.r)
+		if i == len(glyphs)-1 || glyphs[i+1].style != g.style {
+			text := sb.String()
+			frags = append(frags, fragment{
+				text:  text,
+				style: g.style,
+				width: font.MeasureString(b.styles[g.style].face, text),
+			})
+			sb.Reset()
+		}
+	}
+	return frags
+}
+
+// Ширина символов
+func (b *block) measure(glyphs []glyph) fixed.Int26_6 {
+	var w fixed.Int26_6
+	for _, f := range b.fragments(glyphs) {
+		w += f.width
+	}
+	return w
+}
+
+// Возвращает количество первых символов шириной не больше limit,
+// но не меньше одного
+func (b *block) fitGlyphs(glyphs []glyph, limit fixed.Int26_6) int {
+	n := 1
+	for n < len(glyphs) && b.measure(glyphs[:n+1]) <= limit {
+		n++
+	}
+	return n
+}
+
+// Добавляет строку, вычисляя ее ширину и высоту
+func (b *block) add(line textLine) {
+	b.lines = append(b.lines, line)
+	b.update(len(b.lines) - 1)
+}
+
+// Вычисляет ширину и высоту строки i
+func (b *block) update(i int) {
+	line := &b.lines[i]
+	line.width = b.measure(line.glyphs)
+
+	// Высота строки - наибольшая из высот ее оформлений
+	styles := []int{line.style}
+	if len(line.glyphs) > 0 {
+		styles = styles[:0]
+		for _, g := range line.glyphs {
+			styles = append(styles, g.style)
+		}
+	}
+	line.ascent, line.descent, line.height = 0, 0, 0
+	for _, st := range styles {
+		m := b.styles[st].metrics
+		line.ascent = max(line.ascent, m.Ascent)
+		line.descent = max(line.descent, m.Descent)
+		line.height = max(line.height, m.Height)
+	}
+	b.width = max(b.width, line.width)
+}
+
+// Убирает строки, не помещающиеся по высоте,
+// и обрезает с многоточием строки, не помещающиеся по ширине
+func (b *block) ellipsize(size image.Point) {
+	truncated := false
+	if size.Y > 0 && len(b.lines) > 1 {
+		n := 1
+		for n < len(b.lines) && b.linesHeight(n+1) <= fixed.I(size.Y) {
+			n++
+		}
+		if n < len(b.lines) {
+			b.lines = b.lines[:n]
+			truncated = true
+		}
+	}
+
+	limit := fixed.I(size.X)
+	b.width = 0
+	for i := range b.lines {
+		line := &b.lines[i]
+		cut := truncated && i == len(b.lines)-1
+		if cut || size.X > 0 && line.width > limit {
+			glyphs := line.glyphs
+			dots := func() glyph {
+				if len(glyphs) > 0 {
+					return glyph{'…', glyphs[len(glyphs)-1].style}
+				}
+				return glyph{'…', line.style}
+			}
+			for size.X > 0 && len(glyphs) > 0 && b.measure(concat(glyphs, []glyph{dots()})) > limit {
+				glyphs = glyphs[:len(glyphs)-1]
+			}
+			for len(glyphs) > 0 && unicode.IsSpace(glyphs[len(glyphs)-1].r) {
+				glyphs = glyphs[:len(glyphs)-1]
+			}
+			line.glyphs = concat(glyphs, []glyph{dots()})
+			line.words = nil
+			line.last = true
+		}
+		b.update(i)
+	}
+}
+
+// Рисует размеченный текст в области size
+func (b *block) draw(size image.Point, l Layout) *image.RGBA {
+	if size.X <= 0 {
+		size.X = b.width.Ceil()
+	}
+	if size.Y <= 0 {
+		size.Y = b.height().Ceil()
+	}
+	img := image.NewRGBA(image.Rectangle{Max: size})
+	if len(b.lines) == 0 {
+		return img
+	}
+	width, height := fixed.I(size.X), fixed.I(size.Y)
+
+	// Базовая линия первой строки
+	ascent := b.lines[0].ascent
+	var y fixed.Int26_6
+	switch l.VAlign {
+	case VAlignTop:
+		y = ascent
+	case VAlignBaseline:
+		y = ascent
+		if l.Baseline != 0 {
+			y = fixed.I(l.Baseline)
+		}
+	case VAlignBottom:
+		y = height - b.height() + ascent
+	default:
+		y = (height-b.height())/2 + ascent
+	}
+	y = fixed.I(y.Round())
+
+	for i, line := range b.lines {
+		if i > 0 {
+			y += b.step(i)
+		}
+		baseline := fixed.I(y.Round())
+
+		var x fixed.Int26_6
+		switch l.Align {
+		case AlignRight:
+			x = width - line.width
+		case AlignCenter:
+			x = (width - line.width) / 2
+		}
+		x = fixed.I(x.Round())
+
+		// По ширине слова раздвигаются на весь свободный остаток
+		if l.Align == AlignJustify && !line.last && len(line.words) > 1 {
+			space := b.measure([]glyph{{' ', line.style}})
+			gap := space + (width-line.width)/fixed.Int26_6(len(line.words)-1)
+			for _, word := range line.words {
+				b.drawGlyphs(img, word, line, fixed.Point26_6{X: fixed.I(x.Round()), Y: baseline})
+				x += b.measure(word) + gap
+			}
+			continue
+		}
+
+		b.drawGlyphs(img, line.glyphs, line, fixed.Point26_6{X: x, Y: baseline})
+	}
+
+	return img
+}
+
+// Рисует символы строки line, начиная с точки dot на базовой линии
+func (b *block) drawGlyphs(img *image.RGBA, glyphs []glyph, line textLine, dot fixed.Point26_6) {
+	frags := b.fragments(glyphs)
+
+	// Фон фрагментов на всю высоту строки
+	x := dot.X
+	for _, f := range frags {
+		if back := b.styles[f.style].back; back != nil {
+			r := image.Rect(x.Round(), (dot.Y - line.ascent).Round(),
+				(x + f.width).Round(), (dot.Y + line.descent).Round())
+			draw.Draw(img, r, image.NewUniform(back), image.Point{}, draw.Over)
+		}
+		x += f.width
+	}
+
+	drawer := font.Drawer{Dst: img, Dot: dot}
+	for _, f := range frags {
+		s := b.styles[f.style]
+		drawer.Face = s.face
+		drawer.Src = s.src
+		drawer.DrawString(f.text)
+	}
+}
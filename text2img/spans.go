@@ -0,0 +1,213 @@
+package text2img
+
+import (
+	"encoding/binary"
+	"fmt"
+	"image/color"
+	"math"
+	"strconv"
+	"strings"
+
+	"golang.org/x/image/font"
+)
+
+// Фрагмент текста со своим оформлением.
+// Незаданные поля берутся из оформления надписи: пустое семейство,
+// нулевые размер и насыщенность - из шрифта надписи, цвет nil - цвет надписи
+type Span struct {
+	Text       string
+	Font       Font
+	Color      color.Color
+	Background color.Color // Фон фрагмента, nil - без фона
+
+	// Насыщенность Font.Weight задана явно, даже если она WeightNormal.
+	// Нужна для обычного фрагмента в надписи жирным шрифтом
+	WeightSet bool
+}
+
+// Шрифт фрагмента с незаданными полями из шрифта надписи base
+func (s Span) font(base Font) Font {
+	f := s.Font
+	if f.Family == "" {
+		f.Family = base.Family
+	}
+	if f.Weight == font.WeightNormal && !s.WeightSet {
+		f.Weight = base.Weight
+	}
+	if f.Size <= 0 {
+		f.Size = base.Size
+	}
+	return f
+}
+
+// Ключ фрагментов в кэше рендеров. Поля фрагментов записываются подряд,
+// цвета приводятся к RGBA, поэтому одинаковые цвета разных типов дают один ключ
+func spansKey(spans []Span) string {
+	var b []byte
+	for _, s := range spans {
+		b = appendKeyString(b, s.Text)
+		b = appendKeyString(b, s.Font.Family)
+		b = binary.AppendVarint(b, int64(s.Font.Weight))
+		b = binary.LittleEndian.AppendUint64(b, math.Float64bits(s.Font.Size))
+		b = appendKeyColor(b, s.Color)
+		b = appendKeyColor(b, s.Background)
+		if s.WeightSet {
+			b = append(b, 1)
+		} else {
+			b = append(b, 0)
+		}
+	}
+	return string(b)
+}
+
+func appendKeyString(b []byte, s string) []byte {
+	b = binary.AppendUvarint(b, uint64(len(s)))
+	return append(b, s...)
+}
+
+func appendKeyColor(b []byte, c color.Color) []byte {
+	if c == nil {
+		return append(b, 0)
+	}
+	rgba := color.RGBAModel.Convert(c).(color.RGBA)
+	return append(b, 1, rgba.R, rgba.G, rgba.B, rgba.A)
+}
+
+// Разбирает разметку в список фрагментов:
+//
+//	**жирный** или [b]жирный[/b]
+//	[color=#c00000]красный[/color]
+//	[bg=#ffff00]на желтом фоне[/bg]
+//	[size=24]крупный[/size]
+//	[font=Go Mono]моноширинный[/font]
+//
+// Теги вкладываются друг в друга, закрывающий тег должен соответствовать
+// последнему открытому. Символы [ и * задаются как [[ и [*].
+// Цвета задаются как #RGB, #RRGGBB или #RRGGBBAA
+func ParseMarkup(markup string) ([]Span, error) {
+	type tag struct {
+		name string
+		span Span // Оформление до тега
+	}
+
+	var spans []Span
+	var stack []tag
+	var cur Span
+	var text strings.Builder
+
+	flush := func() {
+		if text.Len() == 0 {
+			return
+		}
+		s := cur
+		s.Text = text.String()
+		spans = append(spans, s)
+		text.Reset()
+	}
+
+	open := func(name string, next Span) {
+		flush()
+		stack = append(stack, tag{name, cur})
+		cur = next
+	}
+
+	closeTag := func(name string) error {
+		if len(stack) == 0 || stack[len(stack)-1].name != name {
+			return fmt.Errorf("text2img: markup: unexpected closing tag %q", name)
+		}
+		flush()
+		cur = stack[len(stack)-1].span
+		stack = stack[:len(stack)-1]
+		return nil
+	}
+
+	for i := 0; i < len(markup); {
+		rest := markup[i:]
+		switch {
+		case strings.HasPrefix(rest, "[["):
+			text.WriteByte('[')
+			i += 2
+
+		case strings.HasPrefix(rest, "[*]"):
+			text.WriteByte('*')
+			i += 3
+
+		case strings.HasPrefix(rest, "**"):
+			if len(stack) > 0 && stack[len(stack)-1].name == "**" {
+				closeTag("**")
+			} else {
+				next := cur
+				next.Font.Weight = font.WeightBold
+				open("**", next)
+			}
+			i += 2
+
+		case rest[0] == '[':
+			end := strings.IndexByte(rest, ']')
+			if end < 0 {
+				return nil, fmt.Errorf("text2img: markup: unclosed tag at %d", i)
+			}
+			body := rest[1:end]
+			i += end + 1
+
+			if name, ok := strings.CutPrefix(body, "/"); ok {
+				if err := closeTag(name); err != nil {
+					return nil, err
+				}
+				continue
+			}
+
+			name, value, _ := strings.Cut(body, "=")
+			next := cur
+			switch name {
+			case "b":
+				next.Font.Weight = font.WeightBold
+			case "color", "bg":
+				c, err := ParseColor(value)
+				if err != nil {
+					return nil, err
+				}
+				if name == "color" {
+					next.Color = c
+				} else {
+					next.Background = c
+				}
+			case "size":
+				size, err := strconv.ParseFloat(value, 64)
+				if err != nil || size <= 0 {
+					return nil, fmt.Errorf("text2img: markup: bad size %q", value)
+				}
+				next.Font.Size = size
+			case "font":
+				next.Font.Family = value
+			default:
+				return nil, fmt.Errorf("text2img: markup: unknown tag %q", name)
+			}
+			open(name, next)
+
+		default:
+			text.WriteByte(markup[i])
+			i++
+		}
+	}
+	flush()
+
+	// Незакрытые теги действуют до конца текста
+	return spans, nil
+}
+
+// Разбирает цвет #RGB, #RRGGBB или #RRGGBBAA
+func ParseColor(s string) (color.Color, error) {
+	hex, ok := strings.CutPrefix(s, "#")
+	if ok && len(hex) == 3 {
+		hex = string([]byte{hex[0], hex[0], hex[1], hex[1], hex[2], hex[2]})
+	}
+	if ok && len(hex) == 6 {
+		hex += "ff"
+	}
+	v, err := strconv.ParseUint(hex, 16, 32)
+	if !ok || len(hex) != 8 || err != nil {
+		return nil, fmt.Errorf("text2img: bad color %q", s)
+	}
+	return color.NRGBA{uint8(v >> 24), uint8(v >> 16), uint8(v >> 8), uint8(v)}, nil
+}
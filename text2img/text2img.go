@@ -5,51 +5,110 @@ package text2img
 import (
 	"image"
 	"image/color"
-	"log"
+	"log/slog"
 
+	"github.com/anatolypaw/sgui/rendercache"
 	"golang.org/x/image/font"
-	"golang.org/x/image/font/gofont/goregular"
-	"golang.org/x/image/font/opentype"
 	"golang.org/x/image/math/fixed"
 )
 
-func Text2img(label string, size float64, color color.Color) *image.RGBA {
-	fnt, err := opentype.Parse(goregular.TTF)
-	if err != nil {
-		log.Fatalf("Parse: %v", err)
+// Ключ надписи в кэше рендеров
+type textKey struct {
+	label   string
+	font    Font
+	color   color.RGBA
+	version uint64 // Номер изменения реестра шрифтов
+}
+
+// Рисует надпись шрифтом DefaultFamily размера size.
+// Базовая линия на высоте Measure().Ascent от верхнего края
+func Text2img(label string, size float64, c color.Color) *image.RGBA {
+	return DrawText(label, Font{Size: size}, c)
+}
+
+// Рисует надпись шрифтом f. Если цвет nil, то белым.
+// Рендеры кэшируются по тексту, шрифту и цвету, возвращается копия.
+// Если шрифт не удалось создать, то возвращается пустое изображение
+func DrawText(label string, f Font, c color.Color) *image.RGBA {
+	if c == nil {
+		c = color.White
 	}
+	if f.Family == "" {
+		f.Family = DefaultFamily
+	}
+
+	fonts.mu.Lock()
+	defer fonts.mu.Unlock()
 
-	face, err := opentype.NewFace(fnt, &opentype.FaceOptions{
-		Size:    float64(size),
-		DPI:     72,
-		Hinting: font.HintingNone,
+	key := textKey{label, f, color.RGBAModel.Convert(c).(color.RGBA), fonts.version}
+	return rendercache.GetCopy(key, func() *image.RGBA {
+		return fonts.draw(label, f, key.color)
 	})
+}
 
-	if err != nil {
-		log.Fatalf("NewFace: %v", err)
+// Размеры однострочной надписи в пикселях.
+// Вертикальные размеры отсчитываются от базовой линии
+type Metrics struct {
+	Advance int // Смещение пера после надписи
+	Ascent  int // Высота шрифта над базовой линией
+	Descent int // Глубина шрифта под базовой линией
+	Height  int // Рекомендуемое расстояние между базовыми линиями строк
+
+	// Границы закрашенных пикселей относительно начала базовой линии,
+	// верх отрицательный
+	Bounds image.Rectangle
+}
+
+// Возвращает размеры надписи text шрифтом f
+func Measure(text string, f Font) Metrics {
+	if f.Family == "" {
+		f.Family = DefaultFamily
 	}
 
-	metrics := face.Metrics()
-	meas := font.MeasureString(face, label)
+	fonts.mu.Lock()
+	defer fonts.mu.Unlock()
+
+	face, err := fonts.face(f)
+	if err != nil {
+		return Metrics{}
+	}
+	return measure(face, text)
+}
 
-	var uniform *image.Uniform
+func measure(face font.Face, text string) Metrics {
+	m := face.Metrics()
+	bounds, advance := font.BoundString(face, text)
+	return Metrics{
+		Advance: advance.Ceil(),
+		Ascent:  m.Ascent.Ceil(),
+		Descent: m.Descent.Ceil(),
+		Height:  m.Height.Ceil(),
+		Bounds: image.Rect(bounds.Min.X.Floor(), bounds.Min.Y.Floor(),
+			bounds.Max.X.Ceil(), bounds.Max.Y.Ceil()),
+	}
+}
 
-	if color != nil {
-		uniform = image.NewUniform(color)
-	} else {
-		uniform = image.White
+// Рисует надпись без кэширования. Вызывается под r.mu.
+// Высота изображения - от верха шрифта до низа выносных элементов,
+// базовая линия на высоте Ascent от верхнего края
+func (r *registry) draw(label string, f Font, c color.Color) *image.RGBA {
+	face, err := r.face(f)
+	if err != nil {
+		slog.Error("text2img", "err", err)
+		return image.NewRGBA(image.Rectangle{})
 	}
 
-	img := image.NewRGBA(image.Rect(0, 0, meas.Round(), int(metrics.Height/70)))
+	m := measure(face, label)
+	width := max(m.Advance, m.Bounds.Max.X)
+	img := image.NewRGBA(image.Rect(0, 0, width, m.Ascent+m.Descent))
 	drawer := font.Drawer{
 		Dst:  img,
-		Src:  uniform,
+		Src:  image.NewUniform(c),
 		Face: face,
-		Dot:  fixed.P(0, int(size*0.80)),
+		Dot:  fixed.P(0, m.Ascent),
 	}
 
 	drawer.DrawString(label)
 
 	return img
-
 }
@@ -0,0 +1,289 @@
+package text2img
+
+import (
+	"image"
+	"image/color"
+	"testing"
+
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/gofont/gomono"
+)
+
+// Сумма прозрачности пикселей - количество "краски" в надписи
+func ink(img *image.RGBA) int {
+	n := 0
+	for i := 3; i < len(img.Pix); i += 4 {
+		n += int(img.Pix[i])
+	}
+	return n
+}
+
+func TestFonts(t *testing.T) {
+	if err := Register("broken", font.WeightNormal, []byte("not a font")); err == nil {
+		t.Error("Register accepted invalid data")
+	}
+
+	normal := DrawText("Насос 1", Font{Size: 20}, color.Black)
+	bold := DrawText("Насос 1", Font{Size: 20, Weight: font.WeightBold}, color.Black)
+	if ink(bold) <= ink(normal) {
+		t.Errorf("bold ink %d, normal ink %d", ink(bold), ink(normal))
+	}
+
+	// Отсутствующая насыщенность заменяется ближайшей
+	semi := DrawText("Насос 1", Font{Size: 20, Weight: font.WeightSemiBold}, color.Black)
+	if ink(semi) != ink(bold) {
+		t.Errorf("semibold is not resolved to bold")
+	}
+
+	// Зарегистрированное семейство
+	if err := Register("mono", font.WeightNormal, gomono.TTF); err != nil {
+		t.Fatal(err)
+	}
+	wide := DrawText("iiii", Font{Family: "mono", Size: 20}, color.Black)
+	narrow := DrawText("iiii", Font{Size: 20}, color.Black)
+	if wide.Rect.Dx() <= narrow.Rect.Dx() {
+		t.Errorf("mono width %d, proportional width %d", wide.Rect.Dx(), narrow.Rect.Dx())
+	}
+
+	// Неизвестное семейство рисуется шрифтом по умолчанию
+	if got := DrawText("Насос 1", Font{Family: "missing", Size: 20}, color.Black); ink(got) != ink(normal) {
+		t.Error("unknown family is not replaced by DefaultFamily")
+	}
+
+	// Начертания создаются один раз
+	fonts.mu.Lock()
+	a, _ := fonts.face(Font{Size: 20})
+	b, _ := fonts.face(Font{Size: 20})
+	fonts.mu.Unlock()
+	if a != b {
+		t.Error("face is not cached")
+	}
+
+	// Количество созданных начертаний ограничено
+	for size := 1.0; size <= maxFaces*2; size++ {
+		Measure("1", Font{Size: size})
+	}
+	fonts.mu.Lock()
+	n := len(fonts.faces)
+	fonts.mu.Unlock()
+	if n > maxFaces {
+		t.Errorf("%d faces cached, limit %d", n, maxFaces)
+	}
+
+	SetDPI(144)
+	defer SetDPI(DefaultDPI)
+	if got := DrawText("Насос 1", Font{Size: 20}, color.Black); got.Rect.Dy() < normal.Rect.Dy()*2-1 {
+		t.Errorf("height at 144 DPI %d, at 72 DPI %d", got.Rect.Dy(), normal.Rect.Dy())
+	}
+}
+
+// Границы непрозрачных пикселей
+func inkBounds(img *image.RGBA) image.Rectangle {
+	var r image.Rectangle
+	for y := img.Rect.Min.Y; y < img.Rect.Max.Y; y++ {
+		for x := img.Rect.Min.X; x < img.Rect.Max.X; x++ {
+			if img.RGBAAt(x, y).A > 0 {
+				r = r.Union(image.Rect(x, y, x+1, y+1))
+			}
+		}
+	}
+	return r
+}
+
+func TestLayout(t *testing.T) {
+	f := Font{Size: 16}
+	text := "Давление в напорном трубопроводе превышает норму"
+	one := MeasureLayout("Давление", f, image.Point{}, Layout{})
+
+	// Перенос по словам и явные переводы строк
+	wrapped := MeasureLayout(text, f, image.Point{120, 0}, Layout{Wrap: true})
+	if wrapped.X > 120 || wrapped.Y < one.Y*3 {
+		t.Errorf("wrapped size %v, line %v", wrapped, one)
+	}
+	if got := MeasureLayout("Насос\nКлапан", f, image.Point{}, Layout{}); got.Y < one.Y*2-1 || got.Y > one.Y*3 {
+		t.Errorf("two lines size %v, line %v", got, one)
+	}
+
+	// Выравнивание по горизонтали
+	size := image.Point{200, 30}
+	left := inkBounds(DrawLayout("Насос", f, color.Black, size, Layout{Align: AlignLeft}))
+	right := inkBounds(DrawLayout("Насос", f, color.Black, size, Layout{Align: AlignRight}))
+	center := inkBounds(DrawLayout("Насос", f, color.Black, size, Layout{}))
+	if left.Min.X > 2 || right.Max.X < size.X-2 || center.Min.X <= left.Min.X || center.Max.X >= right.Max.X {
+		t.Errorf("left %v, center %v, right %v", left, center, right)
+	}
+
+	// Выравнивание по вертикали
+	top := inkBounds(DrawLayout("Насос", f, color.Black, image.Point{100, 60}, Layout{VAlign: VAlignTop}))
+	bottom := inkBounds(DrawLayout("Насос", f, color.Black, image.Point{100, 60}, Layout{VAlign: VAlignBottom}))
+	baseline := inkBounds(DrawLayout("Насос", f, color.Black, image.Point{100, 60}, Layout{VAlign: VAlignBaseline, Baseline: 40}))
+	if top.Min.Y > 6 || bottom.Max.Y < 50 || baseline.Max.Y < 40 || baseline.Max.Y > 41 {
+		t.Errorf("top %v, bottom %v, baseline %v", top, bottom, baseline)
+	}
+
+	// По ширине растягиваются все строки, кроме последней
+	justify := DrawLayout(text, f, color.Black, image.Point{150, 200}, Layout{Wrap: true, Align: AlignJustify, VAlign: VAlignTop})
+	first := inkBounds(justify.SubImage(image.Rect(0, 0, 150, one.Y)).(*image.RGBA))
+	if first.Max.X < 148 {
+		t.Errorf("justified line %v", first)
+	}
+
+	// Многоточие: текст не выходит за область
+	area := image.Point{120, one.Y * 2}
+	clipped := DrawLayout(text, f, color.Black, area, Layout{Wrap: true, Overflow: OverflowEllipsis})
+	if got := MeasureLayout(text, f, area, Layout{Wrap: true, Overflow: OverflowEllipsis}); got.X > area.X || got.Y > area.Y {
+		t.Errorf("ellipsis size %v, area %v", got, area)
+	}
+	if clipped.Rect.Size() != area {
+		t.Errorf("image size %v, want %v", clipped.Rect.Size(), area)
+	}
+	line := MeasureLayout(text, f, image.Point{120, 0}, Layout{Overflow: OverflowEllipsis})
+	if line.X > 120 || line.Y > one.Y+1 {
+		t.Errorf("single line ellipsis %v", line)
+	}
+
+	// Уменьшение шрифта до размера области
+	shrunk := MeasureLayout(text, f, image.Point{200, 0}, Layout{Overflow: OverflowShrink, MinSize: 4})
+	if shrunk.X > 200 || shrunk.Y >= one.Y {
+		t.Errorf("shrunk size %v", shrunk)
+	}
+}
+
+func TestMeasure(t *testing.T) {
+	f := Font{Size: 20}
+	m := Measure("Ру", f)
+	if m.Ascent <= 0 || m.Descent <= 0 || m.Advance <= 0 || m.Height < m.Ascent+m.Descent-1 {
+		t.Fatalf("metrics %+v", m)
+	}
+
+	// Буква "у" уходит под базовую линию, "Р" стоит на ней
+	if m.Bounds.Min.Y >= 0 || m.Bounds.Max.Y <= 0 {
+		t.Errorf("bounds %v", m.Bounds)
+	}
+
+	// Выносные элементы не обрезаются
+	img := DrawText("Ру", f, color.Black)
+	if img.Rect.Dy() != m.Ascent+m.Descent {
+		t.Errorf("image height %d, want %d", img.Rect.Dy(), m.Ascent+m.Descent)
+	}
+	if ink := inkBounds(img); ink.Max.Y != m.Ascent+m.Bounds.Max.Y || ink.Max.Y > img.Rect.Dy() {
+		t.Errorf("ink %v, metrics %+v", ink, m)
+	}
+
+	// Базовая линия "Р" на высоте Ascent
+	p := inkBounds(DrawText("Р", f, color.Black))
+	if p.Max.Y != m.Ascent {
+		t.Errorf("baseline at %d, want %d", p.Max.Y, m.Ascent)
+	}
+}
+
+func TestParseMarkup(t *testing.T) {
+	red := color.NRGBA{0xcc, 0, 0, 0xff}
+	spans, err := ParseMarkup("Темп: **82.4** [color=#c00][bg=#ff0]°C[/bg][/color] [size=8][[1][/size]")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Span{
+		{Text: "Темп: "},
+		{Text: "82.4", Font: Font{Weight: font.WeightBold}},
+		{Text: " "},
+		{Text: "°C", Color: red, Background: color.NRGBA{0xff, 0xff, 0, 0xff}},
+		{Text: " "},
+		{Text: "[1]", Font: Font{Size: 8}},
+	}
+	if len(spans) != len(want) {
+		t.Fatalf("spans %+v", spans)
+	}
+	for i := range want {
+		if spans[i] != want[i] {
+			t.Errorf("span %d: %+v, want %+v", i, spans[i], want[i])
+		}
+	}
+
+	for _, bad := range []string{"[b]x[/color]", "[color=red]x", "[size=0]x", "[u]x", "[b"} {
+		if _, err := ParseMarkup(bad); err == nil {
+			t.Errorf("%q: no error", bad)
+		}
+	}
+}
+
+func TestSpansKey(t *testing.T) {
+	a := []Span{{Text: "Насос ", Color: color.RGBA{255, 0, 0, 255}}, {Text: "стоп"}}
+	b := []Span{{Text: "Насос ", Color: color.NRGBA{255, 0, 0, 255}}, {Text: "стоп"}}
+	if spansKey(a) != spansKey(b) {
+		t.Error("equal colors of different types give different keys")
+	}
+
+	for _, other := range [][]Span{
+		{{Text: "Насос", Color: color.RGBA{255, 0, 0, 255}}, {Text: " стоп"}},
+		{{Text: "Насос ", Color: color.RGBA{255, 0, 0, 255}}, {Text: "стоп", WeightSet: true}},
+		{{Text: "Насос ", Background: color.RGBA{255, 0, 0, 255}}, {Text: "стоп"}},
+	} {
+		if spansKey(a) == spansKey(other) {
+			t.Errorf("%+v: same key as %+v", other, a)
+		}
+	}
+}
+
+func TestSpans(t *testing.T) {
+	red := color.RGBA{255, 0, 0, 255}
+	yellow := color.RGBA{255, 255, 0, 255}
+	f := Font{Size: 16}
+	size := image.Point{200, 40}
+
+	spans := []Span{
+		{Text: "Насос "},
+		{Text: "стоп", Color: red, Background: yellow},
+		{Text: " НОМ", Font: Font{Size: 24}},
+	}
+	layout := Layout{Align: AlignLeft, VAlign: VAlignBaseline, Baseline: 30}
+	img := DrawSpans(spans, f, color.Black, size, layout)
+
+	var reds, yellows int
+	for i := 0; i < len(img.Pix); i += 4 {
+		switch c := (color.RGBA{img.Pix[i], img.Pix[i+1], img.Pix[i+2], img.Pix[i+3]}); {
+		case c == yellow:
+			yellows++
+		case c.R > 200 && c.G < 50:
+			reds++
+		}
+	}
+	if reds == 0 || yellows == 0 {
+		t.Errorf("red pixels %d, background pixels %d", reds, yellows)
+	}
+
+	// Фрагменты разного размера стоят на одной базовой линии
+	small := inkBounds(DrawSpans(spans[:1], f, color.Black, size, layout))
+	line := inkBounds(img.SubImage(image.Rect(0, 0, small.Max.X, size.Y)).(*image.RGBA))
+	if line != small {
+		t.Errorf("baseline of mixed sizes %v, single size %v", line, small)
+	}
+
+	// Перенос по словам учитывает все фрагменты
+	one := MeasureSpans(spans, f, image.Point{}, Layout{})
+	wrapped := MeasureSpans(spans, f, image.Point{90, 0}, Layout{Wrap: true})
+	if wrapped.X > 90 || wrapped.Y <= one.Y {
+		t.Errorf("wrapped spans %v, single line %v", wrapped, one)
+	}
+
+	// Простой текст и один фрагмент рисуются одинаково
+	plain := DrawLayout("Насос стоп", f, color.Black, size, Layout{})
+	span := DrawSpans([]Span{{Text: "Насос стоп"}}, f, color.Black, size, Layout{})
+	if string(plain.Pix) != string(span.Pix) {
+		t.Error("single span differs from plain text")
+	}
+
+	// Фрагмент с явной обычной насыщенностью в надписи жирным шрифтом
+	bold := Font{Size: 16, Weight: font.WeightBold}
+	normal := Span{Text: "Насос стоп", WeightSet: true}
+	if got := normal.font(bold).Weight; got != font.WeightNormal {
+		t.Errorf("explicit weight %v, want normal", got)
+	}
+	if got := (Span{Text: "Насос стоп"}).font(bold).Weight; got != font.WeightBold {
+		t.Errorf("inherited weight %v, want bold", got)
+	}
+	forced := DrawSpans([]Span{normal}, bold, color.Black, size, Layout{})
+	if string(forced.Pix) != string(plain.Pix) {
+		t.Error("explicit normal span in a bold label differs from normal text")
+	}
+}
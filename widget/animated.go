@@ -0,0 +1,385 @@
+package widget
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/gif"
+	"time"
+
+	"github.com/anatolypaw/sgui/painter"
+)
+
+// Задержка кадра, если в GIF она не указана
+const DefaultFrameDelay = 100 * time.Millisecond
+
+// Анимация: последовательность кадров с задержками
+type Clip struct {
+	Frames []image.Image
+	Delays []time.Duration // Время показа каждого кадра
+
+	// Сколько раз проигрывать анимацию, 0 - бесконечно.
+	// После последнего проигрывания остается последний кадр
+	Loops int
+}
+
+// Декодирует анимацию из GIF.
+// Кадры собираются с учетом способа удаления предыдущего кадра
+func ClipFromGIF(data []byte) (Clip, error) {
+	g, err := gif.DecodeAll(bytes.NewReader(data))
+	if err != nil {
+		return Clip{}, err
+	}
+
+	clip := Clip{}
+	switch {
+	case g.LoopCount == 0:
+		clip.Loops = 0
+	case g.LoopCount < 0:
+		clip.Loops = 1
+	default:
+		clip.Loops = g.LoopCount + 1
+	}
+
+	canvas := image.NewRGBA(image.Rect(0, 0, g.Config.Width, g.Config.Height))
+	for i, frame := range g.Image {
+		var disposal byte
+		if i < len(g.Disposal) {
+			disposal = g.Disposal[i]
+		}
+
+		var previous *image.RGBA
+		if disposal == gif.DisposalPrevious {
+			previous = cloneRGBA(canvas)
+		}
+
+		draw.Draw(canvas, frame.Bounds(), frame, frame.Bounds().Min, draw.Over)
+		clip.Frames = append(clip.Frames, cloneRGBA(canvas))
+
+		delay := time.Duration(g.Delay[i]) * 10 * time.Millisecond
+		if delay <= 0 {
+			delay = DefaultFrameDelay
+		}
+		clip.Delays = append(clip.Delays, delay)
+
+		switch disposal {
+		case gif.DisposalBackground:
+			draw.Draw(canvas, frame.Bounds(), image.Transparent, image.Point{}, draw.Src)
+		case gif.DisposalPrevious:
+			canvas = previous
+		}
+	}
+
+	return clip, nil
+}
+
+// Нарезает анимацию из листа спрайтов.
+// Ячейки размера cell идут слева направо и сверху вниз,
+// count - количество кадров (0 - все ячейки листа), delay - время показа кадра
+func ClipFromSpriteSheet(sheet image.Image, cell image.Point, count int, delay time.Duration) Clip {
+	clip := Clip{}
+	b := sheet.Bounds()
+	if cell.X <= 0 || cell.Y <= 0 {
+		return clip
+	}
+
+	for y := b.Min.Y; y+cell.Y <= b.Max.Y; y += cell.Y {
+		for x := b.Min.X; x+cell.X <= b.Max.X; x += cell.X {
+			if count > 0 && len(clip.Frames) == count {
+				return clip
+			}
+
+			frame := image.NewRGBA(image.Rectangle{Max: cell})
+			draw.Draw(frame, frame.Rect, sheet, image.Point{x, y}, draw.Src)
+			clip.Frames = append(clip.Frames, frame)
+			clip.Delays = append(clip.Delays, delay)
+		}
+	}
+
+	return clip
+}
+
+// Общая длительность одного проигрывания
+func (c Clip) duration() time.Duration {
+	var d time.Duration
+	for _, delay := range c.Delays {
+		d += delay
+	}
+	return d
+}
+
+// Возвращает кадр в момент elapsed от начала проигрывания
+// и false, если проигрывание закончилось
+func (c Clip) frameAt(elapsed time.Duration) (int, bool) {
+	total := c.duration()
+	if len(c.Frames) == 0 || total <= 0 {
+		return 0, false
+	}
+
+	if c.Loops > 0 && elapsed >= total*time.Duration(c.Loops) {
+		return len(c.Frames) - 1, false
+	}
+
+	elapsed %= total
+	for i, delay := range c.Delays {
+		if elapsed < delay {
+			return i, true
+		}
+		elapsed -= delay
+	}
+	return len(c.Frames) - 1, true
+}
+
+// Анимированное изображение: работающий конвейер, вращающийся вентилятор.
+// Виджет содержит несколько анимаций, StateSource выбирает проигрываемую.
+// Кадры меняются по часам кадров, поэтому анимации синхронны с отрисовкой
+type Animated struct {
+	size image.Point
+	mode painter.ScaleMode
+	back color.Color
+
+	clips   []Clip
+	renders [][]*image.RGBA // Отмасштабированные кадры анимаций
+
+	clip    int           // Текущая анимация
+	frame   int           // Текущий кадр
+	playing bool          // Анимация проигрывается
+	start   time.Time     // Момент начала проигрывания по часам кадров
+	offset  time.Duration // Время проигрывания до паузы
+
+	// Если функция передана, то номер анимации берется из нее перед рендерингом.
+	// При смене анимации она проигрывается с начала
+	StateSource func() int
+
+	// Таймеры и анимации по часам кадров
+	clocked
+
+	hidden         bool
+	disabled       bool
+	disabledRender disabledCache // Рендер отключенного виджета
+
+	// Флаг, что изображение изменилось.
+	// Сбрасывется после рендеринга
+	updated bool
+}
+
+// Создает анимированное изображение с анимациями clips.
+// Первая анимация сразу начинает проигрываться
+func NewAnimated(size image.Point, mode painter.ScaleMode, clips ...Clip) *Animated {
+	w := &Animated{
+		size:    size,
+		mode:    mode,
+		updated: true,
+	}
+	for _, c := range clips {
+		w.AddClip(c)
+	}
+	w.Play()
+	return w
+}
+
+// Добавляет анимацию и возвращает ее номер
+func (w *Animated) AddClip(c Clip) int {
+	w.clips = append(w.clips, c)
+	w.renders = append(w.renders, make([]*image.RGBA, len(c.Frames)))
+	return len(w.clips) - 1
+}
+
+// Переключает на анимацию i и проигрывает ее с начала
+func (w *Animated) SetClip(i int) {
+	if i < 0 || i >= len(w.clips) || i == w.clip {
+		return
+	}
+	w.clip = i
+	w.Restart()
+}
+
+// Возвращает номер текущей анимации
+func (w *Animated) Clip() int {
+	return w.clip
+}
+
+// Проигрывает текущую анимацию с начала
+func (w *Animated) Restart() {
+	w.offset = 0
+	w.frame = 0
+	w.anchor()
+	w.updated = true
+}
+
+// Продолжает проигрывание.
+// Закончившаяся анимация проигрывается с начала
+func (w *Animated) Play() {
+	if w.playing {
+		return
+	}
+	if w.clip < len(w.clips) {
+		if _, playing := w.clips[w.clip].frameAt(w.offset); !playing {
+			w.offset = 0
+		}
+	}
+	w.playing = true
+	w.anchor()
+}
+
+// Отсчитывает начало проигрывания от текущего кадра с учетом offset.
+// До привязки к часам кадров начало отсчитывается при первом обновлении
+func (w *Animated) anchor() {
+	if w.timers.Clock() == nil {
+		w.start = time.Time{}
+		return
+	}
+	w.start = w.timers.Now().Add(-w.offset)
+}
+
+// Приостанавливает проигрывание на текущем кадре
+func (w *Animated) Pause() {
+	if !w.playing {
+		return
+	}
+	w.playing = false
+	if !w.start.IsZero() {
+		w.offset = w.timers.Since(w.start)
+	}
+}
+
+// Останавливает проигрывание и возвращает к первому кадру
+func (w *Animated) Stop() {
+	w.playing = false
+	w.offset = 0
+	if w.frame != 0 {
+		w.frame = 0
+		w.updated = true
+	}
+}
+
+// Возвращает true, если анимация проигрывается
+func (w *Animated) Playing() bool {
+	return w.playing
+}
+
+// Устанавливает способ вписывания кадров
+func (w *Animated) SetMode(mode painter.ScaleMode) {
+	if w.mode == mode {
+		return
+	}
+	w.mode = mode
+	w.clearCache()
+}
+
+// Устанавливает цвет областей, не закрытых кадром.
+// Если nil, то они прозрачные
+func (w *Animated) SetBackground(c color.Color) {
+	if w.back == c {
+		return
+	}
+	w.back = c
+	w.clearCache()
+}
+
+// Удаляет отмасштабированные кадры
+func (w *Animated) clearCache() {
+	for i := range w.renders {
+		w.renders[i] = make([]*image.RGBA, len(w.clips[i].Frames))
+	}
+	w.updated = true
+}
+
+func (w *Animated) Update() {
+	if w.StateSource != nil {
+		w.SetClip(w.StateSource())
+	}
+
+	if !w.playing || w.clip >= len(w.clips) {
+		return
+	}
+	if w.start.IsZero() {
+		w.anchor()
+	}
+
+	frame, playing := w.clips[w.clip].frameAt(w.timers.Since(w.start))
+	if !playing {
+		w.Pause()
+	}
+	if frame != w.frame {
+		w.frame = frame
+		w.updated = true
+	}
+}
+
+func (w *Animated) Render() *image.RGBA {
+	w.updated = false
+
+	if w.clip >= len(w.clips) || w.frame >= len(w.renders[w.clip]) {
+		return image.NewRGBA(image.Rectangle{Max: w.size})
+	}
+
+	render := w.renders[w.clip][w.frame]
+	if render == nil {
+		render = painter.ScaleImage(w.clips[w.clip].Frames[w.frame], w.size, w.mode, w.back)
+		w.renders[w.clip][w.frame] = render
+	}
+
+	if w.disabled {
+		return w.disabledRender.get(render)
+	}
+	return render
+}
+
+func (w *Animated) Size() image.Point {
+	return w.size
+}
+
+func (w *Animated) Updated() bool {
+	return w.updated
+}
+
+func (w *Animated) Tap(pos image.Point) {
+}
+
+func (w *Animated) Release(pos image.Point) {
+}
+
+func (w *Animated) Hide() {
+	w.hidden = true
+}
+
+func (w *Animated) Show() {
+	if !w.hidden {
+		return
+	}
+	w.hidden = false
+	w.updated = true
+}
+
+func (w *Animated) Hidden() bool {
+	return w.hidden
+}
+
+func (w *Animated) Enable() {
+	if !w.disabled {
+		return
+	}
+	w.disabled = false
+	w.updated = true
+}
+
+func (w *Animated) Disable() {
+	if w.disabled {
+		return
+	}
+	w.disabled = true
+	w.updated = true
+}
+
+func (w *Animated) Disabled() bool {
+	return w.disabled
+}
+
+// Возвращает копию изображения
+func cloneRGBA(img *image.RGBA) *image.RGBA {
+	out := image.NewRGBA(img.Rect)
+	copy(out.Pix, img.Pix)
+	return out
+}
@@ -0,0 +1,25 @@
+package widget
+
+import (
+	"image"
+	"time"
+
+	"github.com/anatolypaw/sgui/anim"
+)
+
+// Параметры мигания состояния индикатора.
+// Фаза мигания берется с часов кадров, поэтому все индикаторы
+// с одинаковыми on и off мигают синхронно
+type blink struct {
+	alt *image.RGBA   // Изображение для фазы off
+	on  time.Duration // Длительность фазы on, 0 - мигание отключено
+	off time.Duration // Длительность фазы off
+}
+
+// Возвращает true, если должно отображаться основное изображение
+func (b blink) phase(t *anim.Timers) bool {
+	if b.on <= 0 {
+		return true
+	}
+	return t.Blink(b.on, b.off)
+}
@@ -4,36 +4,36 @@ import (
 	"image"
 	"image/color"
 	"image/draw"
+	"reflect"
+	"time"
 
 	"github.com/anatolypaw/sgui/painter"
 	"github.com/anatolypaw/sgui/text2img"
 )
 
-// Имеет неограниченное количество переключающихся состояний
-// Состояние представляет собой круг заданного цвета
-// 1) Сначала нужно создать состояние через AddState()
-// 2) Для изменения состояния испольузется SetState()
-
+// Кнопка с текстом.
+// Обычная кнопка вызывает OnClick после отпускания.
+// В режиме ButtonToggle каждое нажатие переключает состояние Checked,
+// в режиме ButtonLatch нажатие включает кнопку, а выключается она только программно.
+// Если задан CheckedSource, то отображаемое состояние берется из него,
+// а нажатие только сообщает о желаемом состоянии через OnToggle.
+// В режиме ButtonRepeat OnClick вызывается повторно, пока кнопка удерживается,
+// в режиме ButtonHoldConfirm - только после удержания в течение HoldDuration
 type Button struct {
 	param ButtonParam
 
 	tapped   bool // Флаг, что кнопка нажата
 	disabled bool // флаг, что виджет не воспринимает события
+	checked  bool // Состояние переключателя
+	focused  bool // Кнопка в фокусе
 
 	// Флаг, что изображение изменилось.
 	// Сбрасывется после рендеринга
-	sizeUpdated         bool
-	textUpdated         bool
-	releasedBaseUpdated bool
-	pressedBaseUpdated  bool
-	stateUpdated        bool
-
-	textRender         *image.RGBA
-	releasedRender     *image.RGBA
-	finalRelesedRender *image.RGBA
-	pressedRender      *image.RGBA
-	finalPressedRender *image.RGBA
-	backgroundRender   *image.RGBA // используется, когда кнопка скрыта
+	updated bool
+
+	// Рендеры вида кнопки для отжатого и включенного состояния.
+	// nil - рендер нужно перерисовать
+	faces [faceCount][2]*image.RGBA
 
 	// Если фнкция была передана, то она будет выполняться
 	// каждый раз перед рендерингом.
@@ -41,14 +41,56 @@ type Button struct {
 	// то он будет применен
 	ParamSource func() ButtonParam
 
-	// Счетчик того, сколько раз рендер вернул изображение нажатой кнопки
+	// Если функция передана, то состояние переключателя берется из нее
+	// перед рендерингом, например из значения в контроллере
+	CheckedSource func() bool
+
+	// Время нажатия по часам кадров.
 	// При быстром нажатии и отпускании кнопки, рендер может не попасть на момент,
 	// когда кнопка была нажата и визуально нажатия не будет
-	// поэтому считаем, сколько раз отобразилось нажатое состояние и показываем его
-	// пока не насчитаем нужно значение (указано в функции рендера)
-	TapShowCounter uint
+	// поэтому нажатое состояние показывается не меньше PressFeedback с момента нажатия
+	tapTime      time.Time
+	pressedShown bool // Последний рендер вернул нажатое состояние
+
+	repeats    int         // Количество повторов с момента нажатия в режиме ButtonRepeat
+	confirmed  bool        // Удержание подтверждено в режиме ButtonHoldConfirm
+	holdRender *image.RGBA // Рендер кнопки с заполнением удержания
+
+	// Если задан класс, то оформление берется из текущей темы
+	theme themeBinding
+
+	// Таймеры и анимации по часам кадров
+	clocked
 }
 
+// Вид кнопки
+const (
+	faceNormal = iota
+	facePressed
+	faceDisabled
+	faceHold // Заполнение при удержании в режиме ButtonHoldConfirm
+	faceCount
+)
+
+// Режим работы кнопки
+type ButtonMode int
+
+const (
+	ButtonMomentary   ButtonMode = iota // Кнопка без фиксации
+	ButtonToggle                        // Каждое нажатие переключает состояние
+	ButtonLatch                         // Нажатие включает, выключение только программно
+	ButtonRepeat                        // OnClick повторяется, пока кнопка удерживается
+	ButtonHoldConfirm                   // OnClick после удержания в течение HoldDuration
+)
+
+// Значения по умолчанию
+const (
+	DefaultPressFeedback  = 150 * time.Millisecond // Время отображения нажатого состояния
+	DefaultRepeatDelay    = 500 * time.Millisecond // Задержка перед первым повтором
+	DefaultRepeatInterval = 100 * time.Millisecond // Интервал повторов
+	DefaultHoldDuration   = 2 * time.Second        // Время удержания для подтверждения
+)
+
 type ButtonParam struct {
 	Size             image.Point
 	OnClick          func()
@@ -62,6 +104,61 @@ type ButtonParam struct {
 	StrokeColor      color.Color
 	TextColor        color.Color
 	Hidden           bool
+
+	// Семейство и насыщенность шрифта, размер задает TextSize
+	Font text2img.Font
+
+	// Перенос, выравнивание и межстрочный интервал текста.
+	// По умолчанию одна строка по середине
+	TextLayout text2img.Layout
+
+	// Минимальное время отображения нажатого состояния.
+	// Если 0, то DefaultPressFeedback
+	PressFeedback time.Duration
+
+	Mode ButtonMode
+
+	// Оформление включенного состояния.
+	// Если не заданы, то используются PressFillColor, Text и TextColor
+	CheckedFillColor color.Color
+	CheckedText      string
+	CheckedTextColor color.Color
+
+	// Вызывается после нажатия в режимах ButtonToggle и ButtonLatch
+	// с новым состоянием переключателя
+	OnToggle func(checked bool)
+
+	// Вызываются при нажатии и отпускании кнопки в любом режиме,
+	// например для толчкового движения.
+	// Вызываются синхронно в обработчике события, поэтому должны быть быстрыми.
+	// OnRelease вызывается и при отключении или скрытии нажатой кнопки
+	OnPress   func()
+	OnRelease func()
+
+	// Режим ButtonRepeat: задержка перед первым повтором и интервал повторов.
+	// Если 0, то DefaultRepeatDelay и DefaultRepeatInterval
+	RepeatDelay    time.Duration
+	RepeatInterval time.Duration
+
+	// Режим ButtonHoldConfirm: время удержания, если 0 - DefaultHoldDuration,
+	// и цвет заполнения, показывающего ход удержания.
+	// Если цвет не задан, то используется цвет включенного состояния
+	HoldDuration  time.Duration
+	HoldFillColor color.Color
+
+	// Иконка и иконка нажатой кнопки. Если PressedIcon не задана,
+	// то в нажатом состоянии отображается Icon.
+	// Если IconTint, то иконка окрашивается в цвет текста,
+	// для этого подходят монохромные иконки с прозрачным фоном
+	Icon          image.Image
+	PressedIcon   image.Image
+	IconPlacement IconPlacement
+	IconTint      bool
+
+	// Градиент, тени и фаска основы отжатой и нажатой кнопки.
+	// Включенная кнопка рисуется с эффектами нажатой
+	ReleaseEffects painter.Effects
+	PressEffects   painter.Effects
 }
 
 func NewButton(p *ButtonParam, ps func() ButtonParam) *Button {
@@ -84,20 +181,130 @@ func NewButton(p *ButtonParam, ps func() ButtonParam) *Button {
 	return &button
 }
 
-// Установка параметров виджета
+// Создает кнопку, оформленную текущей темой (класс ClassButton).
+// При смене темы кнопка перерисовывается
+func NewThemedButton(size image.Point, text string, onClick func()) *Button {
+	button := Button{}
+	button.theme.bind(ClassButton)
+	button.SetParam(ButtonParam{
+		Size:    size,
+		Text:    text,
+		OnClick: onClick,
+	})
+	button.Render()
+
+	return &button
+}
+
+// Создает кнопку-переключатель, оформленную текущей темой.
+// Включенное состояние рисуется стилем StateChecked
+func NewThemedToggleButton(size image.Point, text string, checkedText string, onToggle func(bool)) *Button {
+	button := Button{}
+	button.theme.bind(ClassButton)
+	button.SetParam(ButtonParam{
+		Size:        size,
+		Text:        text,
+		CheckedText: checkedText,
+		Mode:        ButtonToggle,
+		OnToggle:    onToggle,
+	})
+	button.Render()
+
+	return &button
+}
+
+// Устанавливает класс оформления из темы, например "button.danger".
+// Пустой класс отключает оформление темой, текущие параметры сохраняются
+func (w *Button) SetClass(class string) {
+	w.theme.bind(class)
+	w.SetParam(w.param)
+}
+
+// Заменяет параметры оформления параметрами из темы
+func (w *Button) applyTheme(p ButtonParam) ButtonParam {
+	normal := w.theme.style(StateNormal)
+	if w.focused {
+		normal = w.theme.style(StateFocused)
+	}
+	pressed := w.theme.style(StatePressed)
+	checked := w.theme.style(StateChecked)
+
+	p.ReleaseFillColor = normal.FillColor
+	p.PressFillColor = pressed.FillColor
+	p.CheckedFillColor = checked.FillColor
+	p.CheckedTextColor = checked.TextColor
+	p.HoldFillColor = checked.FillColor
+	p.CornerRadius = normal.CornerRadius
+	p.StrokeWidth = normal.StrokeWidth
+	p.StrokeColor = normal.StrokeColor
+	p.TextColor = normal.TextColor
+	p.TextSize = normal.TextSize
+	p.Font = normal.Font
+	p.ReleaseEffects = normal.Effects
+	p.PressEffects = pressed.Effects
+	return p
+}
+
+// Устанавливает фокус кнопки, например при управлении энкодером или клавиатурой.
+// Отжатая кнопка в фокусе рисуется стилем темы StateFocused
+func (w *Button) SetFocused(focused bool) {
+	if w.focused == focused {
+		return
+	}
+	w.focused = focused
+	if w.theme.themed() {
+		w.SetParam(w.param)
+	}
+}
+
+// Возвращает true, если кнопка в фокусе
+func (w *Button) Focused() bool {
+	return w.focused
+}
+
+// Установка параметров виджета.
+// Если кнопка оформлена темой, то параметры оформления берутся из темы
 func (w *Button) SetParam(p ButtonParam) {
+	if w.theme.themed() {
+		p = w.applyTheme(p)
+	}
+
 	if w.param.Hidden != p.Hidden {
 		w.param.Hidden = p.Hidden
-		w.stateUpdated = true
+		w.updated = true
+		if p.Hidden {
+			w.cancelPress()
+		}
 	}
 	w.param.OnClick = p.OnClick
+	w.param.OnToggle = p.OnToggle
+	w.param.PressFeedback = p.PressFeedback
+	w.param.Mode = p.Mode
+	w.param.OnPress = p.OnPress
+	w.param.OnRelease = p.OnRelease
+	w.param.RepeatDelay = p.RepeatDelay
+	w.param.RepeatInterval = p.RepeatInterval
+	w.param.HoldDuration = p.HoldDuration
 
 	w.SetSize(p.Size)
 	w.SetBackground(p.BackgroundColor)
 	w.SetText(p.Text, p.TextSize, p.TextColor)
+	w.SetFont(p.Font)
+	w.SetTextLayout(p.TextLayout)
 	w.SetReleaseStyle(p.ReleaseFillColor, p.CornerRadius, p.StrokeWidth, p.StrokeColor)
 	w.SetPressedStyle(p.PressFillColor, p.CornerRadius, p.StrokeWidth, p.StrokeColor)
+	w.SetCheckedStyle(p.CheckedFillColor, p.CheckedText, p.CheckedTextColor)
+	w.SetHoldFill(p.HoldFillColor)
+	w.SetIcon(p.Icon, p.PressedIcon)
+	w.SetIconPlacement(p.IconPlacement, p.IconTint)
+	w.SetEffects(p.ReleaseEffects, p.PressEffects)
+}
 
+// Помечает все рендеры кнопки устаревшими
+func (w *Button) invalidate() {
+	w.faces = [faceCount][2]*image.RGBA{}
+	w.holdRender = nil
+	w.updated = true
 }
 
 // Установить размер
@@ -106,25 +313,20 @@ func (w *Button) SetSize(size image.Point) {
 		return
 	}
 	w.param.Size = size
-	w.sizeUpdated = true
+	w.invalidate()
 }
 
-// Установить задний фон
+// Установить цвет заднего фона под скругленными углами.
+// Если nil, то углы прозрачные и под кнопкой виден экран
 func (w *Button) SetBackground(c color.Color) {
-	// Создаем background для скрытого состояния
-	// Цвет и размер не изменился, пропускаем
-	if w.param.BackgroundColor == c &&
-		!w.sizeUpdated {
+	// Цвет не изменился, пропускаем
+	if w.param.BackgroundColor == c {
 		return
 	}
-	// Обновляем параметры
+
+	// Обновляем параметры, основы будут перерисованы
 	w.param.BackgroundColor = c
-	w.backgroundRender = painter.DrawRectangle(
-		painter.Rectangle{
-			Size:      w.param.Size,
-			BackColor: w.param.BackgroundColor,
-		},
-	)
+	w.invalidate()
 }
 
 // Установить основу для отжатого состояния(подложку)
@@ -139,9 +341,7 @@ func (w *Button) SetReleaseStyle(
 	if w.param.ReleaseFillColor == fillColor &&
 		w.param.CornerRadius == cornerRadius &&
 		w.param.StrokeWidth == strokeWidth &&
-		w.param.StrokeColor == strokeColor &&
-		!w.sizeUpdated &&
-		!w.textUpdated {
+		w.param.StrokeColor == strokeColor {
 		return
 	}
 
@@ -150,23 +350,11 @@ func (w *Button) SetReleaseStyle(
 	w.param.CornerRadius = cornerRadius
 	w.param.StrokeWidth = strokeWidth
 	w.param.StrokeColor = strokeColor
-	w.releasedBaseUpdated = true
-
-	// Создаем рендер основы
-	w.releasedRender = painter.DrawRectangle(
-		painter.Rectangle{
-			Size:         w.param.Size,
-			FillColor:    w.param.ReleaseFillColor,
-			BackColor:    w.param.BackgroundColor,
-			CornerRadius: w.param.CornerRadius,
-			StrokeWidth:  w.param.StrokeWidth,
-			StrokeColor:  w.param.StrokeColor,
-		},
-	)
-
+	w.invalidate()
 }
 
-// Установить основу для нажатого состояния(подложку)
+// Установить основу для нажатого состояния(подложку).
+// Скругление и обводка общие для всех состояний кнопки
 func (w *Button) SetPressedStyle(
 	fillColor color.Color,
 	cornerRadius float64,
@@ -178,28 +366,78 @@ func (w *Button) SetPressedStyle(
 	if w.param.PressFillColor == fillColor &&
 		w.param.CornerRadius == cornerRadius &&
 		w.param.StrokeWidth == strokeWidth &&
-		w.param.StrokeColor == strokeColor &&
-		!w.sizeUpdated {
+		w.param.StrokeColor == strokeColor {
 		return
 	}
+
 	//Обновляем параметры
 	w.param.PressFillColor = fillColor
 	w.param.CornerRadius = cornerRadius
 	w.param.StrokeWidth = strokeWidth
 	w.param.StrokeColor = strokeColor
-	w.pressedBaseUpdated = true
+	w.invalidate()
+}
 
-	// Создаем рендер основы
-	w.pressedRender = painter.DrawRectangle(
-		painter.Rectangle{
-			Size:         w.param.Size,
-			FillColor:    w.param.PressFillColor,
-			BackColor:    w.param.BackgroundColor,
-			CornerRadius: w.param.CornerRadius,
-			StrokeWidth:  w.param.StrokeWidth,
-			StrokeColor:  w.param.StrokeColor,
-		},
-	)
+// Установить оформление включенного состояния.
+// Пустой текст и nil цвета заменяются параметрами отжатой кнопки
+func (w *Button) SetCheckedStyle(fillColor color.Color, text string, textColor color.Color) {
+	if w.param.CheckedFillColor == fillColor &&
+		w.param.CheckedText == text &&
+		w.param.CheckedTextColor == textColor {
+		return
+	}
+
+	w.param.CheckedFillColor = fillColor
+	w.param.CheckedText = text
+	w.param.CheckedTextColor = textColor
+	w.invalidate()
+}
+
+// Установить цвет заполнения при удержании в режиме ButtonHoldConfirm
+func (w *Button) SetHoldFill(c color.Color) {
+	if w.param.HoldFillColor == c {
+		return
+	}
+	w.param.HoldFillColor = c
+	w.invalidate()
+}
+
+// Установить иконки отжатой и нажатой кнопки.
+// nil убирает иконку
+func (w *Button) SetIcon(icon, pressedIcon image.Image) {
+	if w.param.Icon == icon && w.param.PressedIcon == pressedIcon {
+		return
+	}
+	w.param.Icon = icon
+	w.param.PressedIcon = pressedIcon
+	w.invalidate()
+}
+
+// Установить расположение иконки относительно текста
+// и окрашивание ее в цвет текста
+func (w *Button) SetIconPlacement(placement IconPlacement, tint bool) {
+	if w.param.IconPlacement == placement && w.param.IconTint == tint {
+		return
+	}
+	w.param.IconPlacement = placement
+	w.param.IconTint = tint
+	w.invalidate()
+}
+
+// Установить эффекты объема основы отжатой и нажатой кнопки
+func (w *Button) SetEffects(release, press painter.Effects) {
+	if reflect.DeepEqual(w.param.ReleaseEffects, release) &&
+		reflect.DeepEqual(w.param.PressEffects, press) {
+		return
+	}
+	w.param.ReleaseEffects = release
+	w.param.PressEffects = press
+	w.invalidate()
+}
+
+// Установить новый текст, сохранив размер и цвет текста
+func (w *Button) SetCaption(text string) {
+	w.SetText(text, w.param.TextSize, w.param.TextColor)
 }
 
 // Установить новый текст
@@ -208,8 +446,7 @@ func (w *Button) SetText(text string, size float64, color color.Color) {
 	// Если не отличаются, то выходим
 	if w.param.Text == text &&
 		w.param.TextSize == size &&
-		w.param.TextColor == color &&
-		!w.sizeUpdated {
+		w.param.TextColor == color {
 		return
 	}
 
@@ -217,37 +454,110 @@ func (w *Button) SetText(text string, size float64, color color.Color) {
 	w.param.Text = text
 	w.param.TextSize = size
 	w.param.TextColor = color
+	w.invalidate()
+}
 
-	// Создаем рендер текста и вычисляем его расположение
-	// для размещения в середине виджета
-	w.textRender = text2img.Text2img(
-		w.param.Text,
-		w.param.TextSize,
-		w.param.TextColor,
-	)
-	w.textUpdated = true
+// Установить перенос, выравнивание и межстрочный интервал текста
+func (w *Button) SetTextLayout(l text2img.Layout) {
+	if w.param.TextLayout == l {
+		return
+	}
+	w.param.TextLayout = l
+	w.invalidate()
+}
+
+// Установить семейство и насыщенность шрифта
+func (w *Button) SetFont(font text2img.Font) {
+	if w.param.Font == font {
+		return
+	}
+	w.param.Font = font
+	w.invalidate()
+}
+
+// Возвращает состояние переключателя
+func (w *Button) Checked() bool {
+	return w.checked
+}
+
+// Устанавливает состояние переключателя без вызова OnToggle
+func (w *Button) SetChecked(checked bool) {
+	if w.checked == checked {
+		return
+	}
+	w.checked = checked
+	w.updated = true
 }
 
 // Вызвать при нажатии на кнопку
 func (w *Button) Tap(pos image.Point) {
-	if w.tapped {
+	if w.tapped || w.disabled {
+		return
+	}
+
+	// Включенная кнопка с фиксацией не нажимается
+	if w.param.Mode == ButtonLatch && w.checked {
 		return
 	}
 
-	w.TapShowCounter = 3
+	w.tapTime = w.timers.Now()
 	w.tapped = true
-	w.stateUpdated = true
+	w.updated = true
+	w.repeats = 0
+	w.confirmed = false
 
+	if w.param.OnPress != nil {
+		w.param.OnPress()
+	}
+
+	// Первое срабатывание повторяющейся кнопки - сразу при нажатии
+	if w.param.Mode == ButtonRepeat {
+		w.Click()
+	}
 }
 
 // Вызвать при отпускании кнопки
 func (w *Button) Release(pos image.Point) {
+	if !w.tapped {
+		return
+	}
+	w.cancelPress()
+
+	switch w.param.Mode {
+	case ButtonToggle:
+		w.toggle(!w.checked)
+		w.Click()
+	case ButtonLatch:
+		w.toggle(true)
+		w.Click()
+	case ButtonMomentary:
+		w.Click()
+	}
+}
+
+// Отпускает нажатую кнопку без вызова OnClick
+func (w *Button) cancelPress() {
 	if !w.tapped {
 		return
 	}
 	w.tapped = false
-	w.stateUpdated = true
-	w.Click()
+	w.updated = true
+
+	if w.param.OnRelease != nil {
+		w.param.OnRelease()
+	}
+}
+
+// Переключает кнопку в состояние checked и сообщает об этом.
+// Если состояние берется из CheckedSource, то отображение
+// изменится только когда изменится источник
+func (w *Button) toggle(checked bool) {
+	if w.CheckedSource == nil {
+		w.SetChecked(checked)
+	}
+	if w.param.OnToggle != nil {
+		go w.param.OnToggle(checked)
+	}
 }
 
 // Вызвывается когда предварительно нажатая кнопка была отпущенна
@@ -256,6 +566,7 @@ func (w *Button) Click() {
 		go w.param.OnClick()
 	}
 }
+
 func (w *Button) Update() {
 	// Обновляем параметры виджета
 	if w.ParamSource != nil {
@@ -263,96 +574,231 @@ func (w *Button) Update() {
 		w.SetParam(param)
 	}
 
-	// Обновляем счетчик рендеров нажатого состояния
-	if w.TapShowCounter > 0 {
-		w.TapShowCounter--
+	// Сменилась тема, применяем новое оформление
+	if w.theme.changed() {
+		w.SetParam(w.param)
+		w.invalidate()
+	}
 
-		// Произошло последнее уменьшение, отметить кнопку, изменившей изображение
-		if w.TapShowCounter == 0 {
-			w.stateUpdated = true
-		}
+	// Получаем состояние переключателя с внешней функции
+	if w.CheckedSource != nil {
+		w.SetChecked(w.CheckedSource())
+	}
+
+	if w.tapped {
+		w.updateHold()
+	}
+
+	// Кнопка отпущена, а время показа нажатого состояния вышло.
+	// Отмечаем кнопку, изменившей изображение
+	if w.pressedShown && !w.showPressed() {
+		w.updated = true
 	}
 }
 
-// Render implements sgui.IWidget.
-func (w *Button) Render() *image.RGBA {
+// Повторы и подтверждение удержания нажатой кнопки по часам кадров
+func (w *Button) updateHold() {
+	held := w.timers.Since(w.tapTime)
+
+	switch w.param.Mode {
+	case ButtonRepeat:
+		delay := w.param.RepeatDelay
+		if delay == 0 {
+			delay = DefaultRepeatDelay
+		}
+		interval := w.param.RepeatInterval
+		if interval <= 0 {
+			interval = DefaultRepeatInterval
+		}
 
-	// Подгонка размера финальных рендеров
-	if w.sizeUpdated {
-		rect := image.Rect(0, 0, w.param.Size.X, w.param.Size.Y)
-		w.finalRelesedRender = image.NewRGBA(rect)
-		w.finalPressedRender = image.NewRGBA(rect)
-		w.sizeUpdated = false
+		// Если кадры отрисовываются реже интервала, то за кадр
+		// OnClick вызывается один раз, пропущенные повторы не накапливаются
+		if held >= delay+time.Duration(w.repeats)*interval {
+			w.repeats = int((held-delay)/interval) + 1
+			w.Click()
+		}
+
+	case ButtonHoldConfirm:
+		if w.confirmed {
+			return
+		}
+		// Заполнение меняется каждый кадр
+		w.updated = true
+		if held >= w.holdDuration() {
+			w.confirmed = true
+			w.Click()
+		}
 	}
+}
 
-	// Композиция слоев
+// Время удержания для подтверждения
+func (w *Button) holdDuration() time.Duration {
+	if w.param.HoldDuration <= 0 {
+		return DefaultHoldDuration
+	}
+	return w.param.HoldDuration
+}
 
-	// Была изменена основа отжатого состояния
-	if w.releasedBaseUpdated {
-		draw.Draw(w.finalRelesedRender,
-			w.finalRelesedRender.Bounds(),
-			w.releasedRender,
-			image.Point{0, 0},
-			draw.Src)
+// Возвращает true, если нужно отображать нажатое состояние
+func (w *Button) showPressed() bool {
+	if w.tapped {
+		return true
+	}
 
-		// Текст нужно повторно отрисовать на основе
-		w.textUpdated = true
+	feedback := w.param.PressFeedback
+	if feedback == 0 {
+		feedback = DefaultPressFeedback
 	}
+	return w.timers.Since(w.tapTime) < feedback
+}
 
-	// Была изменена основа нажатого состояния
-	if w.pressedBaseUpdated {
-		draw.Draw(w.finalPressedRender,
-			w.finalPressedRender.Bounds(),
-			w.pressedRender,
-			image.Point{0, 0},
-			draw.Src)
-		// Текст нужно повторно отрисовать на основе
-		w.textUpdated = true
+// Render implements sgui.IWidget.
+func (w *Button) Render() *image.RGBA {
+	w.updated = false
+
+	face := faceNormal
+	switch {
+	case w.disabled:
+		face = faceDisabled
+		w.pressedShown = false
+
+	case w.tapped && w.param.Mode == ButtonHoldConfirm:
+		w.pressedShown = true
+		return w.drawHold()
+
+	// Выдает рендер нажатой кнопки, пока не истечет время показа нажатия
+	case w.showPressed():
+		face = facePressed
+		w.pressedShown = true
+
+	default:
+		w.pressedShown = false
 	}
 
-	// Был изменен текст
-	if w.textUpdated {
-		textMidPos := image.Point{
-			X: -(w.param.Size.X - w.textRender.Rect.Dx()) / 2,
-			Y: -(w.param.Size.Y-w.textRender.Rect.Dy())/2 - w.textRender.Rect.Dy()/12,
-		}
+	return w.face(face, w.checked)
+}
 
-		if w.releasedBaseUpdated {
-			draw.Draw(w.finalRelesedRender,
-				w.finalRelesedRender.Bounds(),
-				w.textRender,
-				textMidPos,
-				draw.Over)
-		}
+// Рисует нажатую кнопку, заполненную слева направо
+// пропорционально времени удержания
+func (w *Button) drawHold() *image.RGBA {
+	pressed := w.face(facePressed, w.checked)
+	hold := w.face(faceHold, w.checked)
 
-		if w.pressedBaseUpdated {
-			draw.Draw(w.finalPressedRender,
-				w.finalPressedRender.Bounds(),
-				w.textRender,
-				textMidPos,
-				draw.Over)
-		}
+	if w.holdRender == nil {
+		w.holdRender = image.NewRGBA(pressed.Rect)
+	}
 
-		w.textUpdated = false
+	progress := float64(w.timers.Since(w.tapTime)) / float64(w.holdDuration())
+	if progress > 1 {
+		progress = 1
 	}
+	x := int(progress * float64(pressed.Rect.Dx()))
 
-	w.releasedBaseUpdated = false
-	w.pressedBaseUpdated = false
-	w.stateUpdated = false
+	draw.Draw(w.holdRender, w.holdRender.Rect, pressed, image.Point{}, draw.Src)
+	draw.Draw(w.holdRender, image.Rect(0, 0, x, pressed.Rect.Dy()), hold, image.Point{}, draw.Src)
+	return w.holdRender
+}
 
-	// Виджет скрыт
-	if w.param.Hidden {
-		return w.backgroundRender
+// Возвращает рендер вида кнопки, при необходимости перерисовывая его
+func (w *Button) face(face int, checked bool) *image.RGBA {
+	c := 0
+	if checked {
+		c = 1
 	}
 
-	// Рендер нажатого состояния
-	// Выдает рендер нажатой кнопки, пока не будет показано заданное кол-во показов (в функции tap)
-	if w.tapped || w.TapShowCounter > 0 {
-		return w.finalPressedRender
+	if w.faces[face][c] == nil {
+		w.faces[face][c] = w.drawFace(face, checked)
+	}
+	return w.faces[face][c]
+}
+
+// Рисует вид кнопки
+func (w *Button) drawFace(face int, checked bool) *image.RGBA {
+	fill := w.param.ReleaseFillColor
+	stroke := w.param.StrokeColor
+	strokeWidth := w.param.StrokeWidth
+	radius := w.param.CornerRadius
+	text := w.param.Text
+	textColor := w.param.TextColor
+	icon := w.param.Icon
+	effects := w.param.ReleaseEffects
+
+	if checked {
+		effects = w.param.PressEffects
+		fill = w.param.PressFillColor
+		if w.param.CheckedFillColor != nil {
+			fill = w.param.CheckedFillColor
+		}
+		if w.param.CheckedText != "" {
+			text = w.param.CheckedText
+		}
+		if w.param.CheckedTextColor != nil {
+			textColor = w.param.CheckedTextColor
+		}
 	}
 
-	return w.finalRelesedRender
+	switch face {
+	case facePressed:
+		fill = w.param.PressFillColor
+		effects = w.param.PressEffects
+		if w.param.PressedIcon != nil {
+			icon = w.param.PressedIcon
+		}
 
+	case faceHold:
+		if w.param.PressedIcon != nil {
+			icon = w.param.PressedIcon
+		}
+		fill = w.param.PressFillColor
+		effects = w.param.PressEffects
+		if w.param.CheckedFillColor != nil {
+			fill = w.param.CheckedFillColor
+		}
+		if w.param.HoldFillColor != nil {
+			fill = w.param.HoldFillColor
+		}
+		// Заполнение удержания всегда сплошное
+		effects.FillGradient = nil
+
+	// Если в теме задан стиль StateDisabled, то кнопка рисуется им,
+	// иначе используется приглушенный рендер отжатой кнопки
+	case faceDisabled:
+		style, ok := w.theme.disabledStyle()
+		if !ok {
+			return disabledImage(w.face(faceNormal, checked))
+		}
+		fill = style.FillColor
+		stroke = style.StrokeColor
+		strokeWidth = style.StrokeWidth
+		radius = style.CornerRadius
+		textColor = style.TextColor
+		effects = style.Effects
+	}
+
+	img := painter.DrawRectangle(
+		painter.Rectangle{
+			Size:         w.param.Size,
+			FillColor:    fill,
+			BackColor:    w.param.BackgroundColor,
+			CornerRadius: radius,
+			StrokeWidth:  strokeWidth,
+			StrokeColor:  stroke,
+			Effects:      effects,
+		},
+	)
+	drawContent(img, content{
+		text:      text,
+		font:      textFont(w.param.Font, w.param.TextSize),
+		textColor: textColor,
+		layout:    w.param.TextLayout,
+		padding:   contentPadding(strokeWidth),
+		icon:      icon,
+		placement: w.param.IconPlacement,
+		tint:      w.param.IconTint,
+		gray:      face == faceDisabled,
+	})
+
+	return img
 }
 
 func (w *Button) Size() image.Point {
@@ -361,13 +807,7 @@ func (w *Button) Size() image.Point {
 
 // Используется для определения, нужно ли вызывать функцию рендеринга
 func (w *Button) Updated() bool {
-	updated := w.sizeUpdated ||
-		w.textUpdated ||
-		w.releasedBaseUpdated ||
-		w.pressedBaseUpdated ||
-		w.stateUpdated
-
-	return updated
+	return w.updated
 }
 
 // Скрывает кнопку
@@ -377,17 +817,38 @@ func (w *Button) Hide() {
 	}
 
 	w.param.Hidden = true
+	w.cancelPress()
 }
 
 func (w *Button) Show() {
 	if !w.param.Hidden {
 		return
 	}
-	w.stateUpdated = true
+	w.updated = true
 	w.param.Hidden = false
 
 }
 
+// Включает кнопку
+func (w *Button) Enable() {
+	if !w.disabled {
+		return
+	}
+	w.disabled = false
+	w.updated = true
+}
+
+// Отключает кнопку: она отображается приглушенной и не реагирует на нажатия.
+// Если кнопка была нажата, то нажатие отменяется без вызова OnClick
+func (w *Button) Disable() {
+	if w.disabled {
+		return
+	}
+	w.disabled = true
+	w.updated = true
+	w.cancelPress()
+}
+
 func (w *Button) Disabled() bool {
 	return w.disabled
 }
@@ -0,0 +1,305 @@
+package widget
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+	"slices"
+
+	"github.com/anatolypaw/sgui/painter"
+	"github.com/anatolypaw/sgui/text2img"
+)
+
+// Холст для собственных виджетов: стрелочных приборов, мнемосхем.
+// Функция рисования вызывается только после Invalidate() или если Changed
+// вернула true. Она не рисует сразу, а записывает команды в DrawContext,
+// команды сохраняются и повторяются без ее вызова, например при смене фона
+type Canvas struct {
+	size   image.Point
+	back   color.Color // Фон холста, nil - прозрачный
+	render *image.RGBA
+
+	draw     func(ctx *DrawContext)
+	commands []func(dst *image.RGBA) // Записанные команды рисования
+	dirty    bool                    // Нужно заново вызвать функцию рисования
+	replay   bool                    // Нужно повторить записанные команды
+
+	// Если функция передана, то она вызывается перед рендерингом.
+	// Если она вернула true, то холст перерисовывается
+	Changed func() bool
+
+	// Таймеры и анимации по часам кадров
+	clocked
+
+	hidden         bool
+	disabled       bool
+	disabledRender disabledCache // Рендер отключенного виджета
+
+	// Флаг, что изображение изменилось.
+	// Сбрасывется после рендеринга
+	updated bool
+}
+
+// Создает холст. Функция draw вызывается при первом рендеринге
+// и после каждого Invalidate()
+func NewCanvas(size image.Point, draw func(ctx *DrawContext)) *Canvas {
+	return &Canvas{
+		size:    size,
+		draw:    draw,
+		dirty:   true,
+		updated: true,
+	}
+}
+
+// Помечает холст для перерисовки функцией рисования
+func (w *Canvas) Invalidate() {
+	w.dirty = true
+	w.updated = true
+}
+
+// Устанавливает цвет фона холста. Если nil, то фон прозрачный.
+// Записанные команды повторяются на новом фоне без вызова функции рисования
+func (w *Canvas) SetBackground(c color.Color) {
+	if w.back == c {
+		return
+	}
+	w.back = c
+	w.replay = true
+	w.updated = true
+}
+
+// Устанавливает размер холста.
+// Функция рисования вызывается заново, чтобы подстроиться под размер
+func (w *Canvas) SetSize(size image.Point) {
+	if w.size == size {
+		return
+	}
+	w.size = size
+	w.render = nil
+	w.Invalidate()
+}
+
+func (w *Canvas) Update() {
+	if w.Changed != nil && w.Changed() {
+		w.Invalidate()
+	}
+}
+
+func (w *Canvas) Render() *image.RGBA {
+	if w.render == nil {
+		w.render = image.NewRGBA(image.Rectangle{Max: w.size})
+	}
+
+	if w.dirty {
+		ctx := DrawContext{size: w.size}
+		if w.draw != nil {
+			w.draw(&ctx)
+		}
+		w.commands = ctx.commands
+		w.dirty = false
+		w.replay = true
+	}
+
+	// Очищаем холст и повторяем команды
+	if w.replay {
+		var back image.Image = image.Transparent
+		if w.back != nil {
+			back = image.NewUniform(w.back)
+		}
+		draw.Draw(w.render, w.render.Rect, back, image.Point{}, draw.Src)
+		for _, c := range w.commands {
+			c(w.render)
+		}
+		w.replay = false
+		w.disabledRender.reset()
+	}
+	w.updated = false
+
+	if w.disabled {
+		return w.disabledRender.get(w.render)
+	}
+	return w.render
+}
+
+func (w *Canvas) Size() image.Point {
+	return w.size
+}
+
+func (w *Canvas) Updated() bool {
+	return w.updated
+}
+
+func (w *Canvas) Tap(pos image.Point) {
+}
+
+func (w *Canvas) Release(pos image.Point) {
+}
+
+func (w *Canvas) Hide() {
+	w.hidden = true
+}
+
+func (w *Canvas) Show() {
+	if !w.hidden {
+		return
+	}
+	w.hidden = false
+	w.updated = true
+}
+
+func (w *Canvas) Hidden() bool {
+	return w.hidden
+}
+
+func (w *Canvas) Enable() {
+	if !w.disabled {
+		return
+	}
+	w.disabled = false
+	w.updated = true
+}
+
+func (w *Canvas) Disable() {
+	if w.disabled {
+		return
+	}
+	w.disabled = true
+	w.updated = true
+}
+
+func (w *Canvas) Disabled() bool {
+	return w.disabled
+}
+
+// Контекст рисования холста.
+// Координаты отсчитываются от левого верхнего угла холста
+type DrawContext struct {
+	size     image.Point
+	commands []func(dst *image.RGBA)
+}
+
+// Размер холста
+func (c *DrawContext) Size() image.Point {
+	return c.size
+}
+
+func (c *DrawContext) add(cmd func(dst *image.RGBA)) {
+	c.commands = append(c.commands, cmd)
+}
+
+// Копия обводки для записи в команду: штрихи не должны меняться
+// вместе со срезом вызывающего
+func recordStroke(s painter.Stroke) painter.Stroke {
+	s.Dash = slices.Clone(s.Dash)
+	return s
+}
+
+// Заливает холст цветом
+func (c *DrawContext) Fill(col color.Color) {
+	c.add(func(dst *image.RGBA) {
+		draw.Draw(dst, dst.Rect, image.NewUniform(col), image.Point{}, draw.Over)
+	})
+}
+
+// Отрезок
+func (c *DrawContext) Line(x1, y1, x2, y2 float64, s painter.Stroke) {
+	s = recordStroke(s)
+	c.add(func(dst *image.RGBA) {
+		painter.DrawLine(dst, image.Point{}, x1, y1, x2, y2, s)
+	})
+}
+
+// Ломаная
+func (c *DrawContext) Polyline(points []painter.Point, s painter.Stroke) {
+	points, s = slices.Clone(points), recordStroke(s)
+	c.add(func(dst *image.RGBA) {
+		painter.DrawPolyline(dst, image.Point{}, points, s)
+	})
+}
+
+// Многоугольник
+func (c *DrawContext) Polygon(points []painter.Point, fill color.Color, s painter.Stroke) {
+	points, s = slices.Clone(points), recordStroke(s)
+	c.add(func(dst *image.RGBA) {
+		painter.DrawPolygon(dst, image.Point{}, points, fill, s)
+	})
+}
+
+// Эллипс
+func (c *DrawContext) Ellipse(cx, cy, rx, ry float64, fill color.Color, s painter.Stroke) {
+	s = recordStroke(s)
+	c.add(func(dst *image.RGBA) {
+		painter.DrawEllipse(dst, image.Point{}, cx, cy, rx, ry, fill, s)
+	})
+}
+
+// Дуга окружности, углы в градусах по часовой стрелке от оси X
+func (c *DrawContext) Arc(cx, cy, r, start, sweep float64, s painter.Stroke) {
+	s = recordStroke(s)
+	c.add(func(dst *image.RGBA) {
+		painter.DrawArc(dst, image.Point{}, cx, cy, r, start, sweep, s)
+	})
+}
+
+// Сектор круга, углы в градусах по часовой стрелке от оси X
+func (c *DrawContext) Pie(cx, cy, r, start, sweep float64, fill color.Color, s painter.Stroke) {
+	s = recordStroke(s)
+	c.add(func(dst *image.RGBA) {
+		painter.DrawPie(dst, image.Point{}, cx, cy, r, start, sweep, fill, s)
+	})
+}
+
+// Произвольный контур
+func (c *DrawContext) Path(p *painter.Path, fill color.Color, s painter.Stroke) {
+	p, s = p.Clone(), recordStroke(s)
+	c.add(func(dst *image.RGBA) {
+		painter.DrawPath(dst, image.Point{}, p, fill, s)
+	})
+}
+
+// Скругленный прямоугольник с левым верхним углом в x, y
+func (c *DrawContext) Rectangle(x, y int, r painter.Rectangle) {
+	img := painter.DrawRectangle(r)
+	c.Image(x, y, img)
+}
+
+// Круг с левым верхним углом описанного квадрата в x, y
+func (c *DrawContext) Circle(x, y int, circle painter.Circle) {
+	img := painter.DrawCircle(circle)
+	c.Image(x, y, img)
+}
+
+// Изображение с левым верхним углом в x, y
+func (c *DrawContext) Image(x, y int, img image.Image) {
+	c.add(func(dst *image.RGBA) {
+		drawAt(dst, img, image.Point{x, y})
+	})
+}
+
+// Однострочный текст с левым верхним углом в x, y
+func (c *DrawContext) Text(x, y int, text string, size float64, col color.Color) {
+	if text == "" {
+		return
+	}
+	img := text2img.Text2img(text, size, col)
+	c.Image(x, y, img)
+}
+
+// Однострочный текст с серединой в cx, cy
+func (c *DrawContext) TextCentered(cx, cy int, text string, size float64, col color.Color) {
+	if text == "" {
+		return
+	}
+	img := text2img.Text2img(text, size, col)
+	c.Image(cx-img.Rect.Dx()/2, cy-img.Rect.Dy()/2, img)
+}
+
+// Однострочный текст шрифтом f с началом базовой линии в x, y.
+// Надписи разных размеров с одним y стоят на одной линии
+func (c *DrawContext) TextBaseline(x, y int, text string, f text2img.Font, col color.Color) {
+	if text == "" {
+		return
+	}
+	img := text2img.DrawText(text, f, col)
+	c.Image(x, y-text2img.Measure(text, f).Ascent, img)
+}
@@ -0,0 +1,21 @@
+package widget
+
+import "github.com/anatolypaw/sgui/anim"
+
+// Таймеры и анимации виджета по часам кадров.
+// Встраивается в виджеты, реализует sgui.ITimedWidget
+type clocked struct {
+	timers anim.Timers
+}
+
+// Привязывает виджет к часам кадров, вызывается Sgui при отрисовке.
+// nil останавливает таймеры и анимации виджета
+func (c *clocked) SetClock(clock *anim.Clock) {
+	c.timers.SetClock(clock)
+}
+
+// Возвращает таймеры и анимации виджета.
+// Они останавливаются при удалении виджета с экрана
+func (c *clocked) Timers() *anim.Timers {
+	return &c.timers
+}
@@ -0,0 +1,54 @@
+package widget
+
+import (
+	"image"
+	"image/color"
+
+	"github.com/anatolypaw/sgui/painter"
+)
+
+// Насколько приглушаются цвета отключенного виджета
+// 0 - только обесцвечивание, 1 - полностью серый
+const DisabledFade = 0.5
+
+// Возвращает приглушенную серую копию рендера для отключенного состояния.
+// Используется, если в теме нет стиля StateDisabled для класса виджета
+func disabledImage(img *image.RGBA) *image.RGBA {
+	if img == nil {
+		return nil
+	}
+	return painter.Grayscale(img, DisabledFade)
+}
+
+// Возвращает серый цвет той же яркости, приближенный к среднему серому
+func fadeColor(c color.Color, fade float64) color.Color {
+	if c == nil {
+		return nil
+	}
+	img := image.NewRGBA(image.Rect(0, 0, 1, 1))
+	img.Set(0, 0, c)
+	return painter.Grayscale(img, fade).At(0, 0)
+}
+
+// Приглушенный рендер отключенного виджета.
+// Перерисовывается только при смене исходного рендера
+type disabledCache struct {
+	src *image.RGBA // Рендер, из которого получен img
+	img *image.RGBA
+}
+
+// Возвращает приглушенную копию рендера src
+func (c *disabledCache) get(src *image.RGBA) *image.RGBA {
+	if c.img == nil || c.src != src {
+		c.src = src
+		c.img = disabledImage(src)
+	}
+	return c.img
+}
+
+// Сбрасывает приглушенный рендер, например после перерисовки
+// исходного рендера на месте
+func (c *disabledCache) reset() {
+	c.src = nil
+	c.img = nil
+}
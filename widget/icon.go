@@ -0,0 +1,177 @@
+package widget
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+	"math"
+
+	"github.com/anatolypaw/sgui/painter"
+	"github.com/anatolypaw/sgui/text2img"
+)
+
+// Расположение иконки относительно текста
+type IconPlacement int
+
+const (
+	IconLeft  IconPlacement = iota // Слева от текста
+	IconRight                      // Справа от текста
+	IconAbove                      // Над текстом
+	IconOnly                       // Только иконка, текст не отображается
+)
+
+// Расстояние между иконкой и текстом
+const iconGap = 6
+
+// Загружает иконку из PNG или JPEG
+func LoadIcon(data []byte) (image.Image, error) {
+	return painter.DecodeImage(data)
+}
+
+// Отступ текста от края виджета без учета обводки
+const textPadding = 4
+
+// Содержимое виджета: текст и иконка
+type content struct {
+	text      string
+	spans     []text2img.Span // Если заданы, то рисуются вместо text
+	font      text2img.Font   // Шрифт с размером текста
+	textColor color.Color
+	layout    text2img.Layout
+	padding   int // Отступ текста и иконки от края
+
+	icon      image.Image
+	placement IconPlacement
+	tint      bool // Окрасить иконку в цвет текста
+	gray      bool // Обесцветить не окрашиваемую иконку
+}
+
+// Шрифт font размера size
+func textFont(font text2img.Font, size float64) text2img.Font {
+	font.Size = size
+	return font
+}
+
+// Отступ содержимого от края виджета с обводкой ширины strokeWidth
+func contentPadding(strokeWidth float64) int {
+	return textPadding + int(math.Ceil(max(0, strokeWidth)))
+}
+
+// Рисует текст и иконку в середине изображения.
+// Текст размечается в области без отступов и места под иконку
+func drawContent(dst *image.RGBA, c content) {
+	size := dst.Rect.Size()
+	hasText := (c.text != "" || len(c.spans) > 0) && (c.placement != IconOnly || c.icon == nil)
+
+	var icon image.Image
+	if c.icon != nil {
+		icon = c.icon
+		switch {
+		case c.tint:
+			textColor := c.textColor
+			if textColor == nil {
+				textColor = color.White
+			}
+			icon = painter.Tint(icon, textColor)
+		case c.gray:
+			icon = painter.Grayscale(toRGBA(icon), DisabledFade)
+		}
+	}
+
+	var iconSize image.Point
+	if icon != nil {
+		iconSize = icon.Bounds().Size()
+	}
+
+	if !hasText {
+		if icon != nil {
+			drawAt(dst, icon, size.Sub(iconSize).Div(2))
+		}
+		return
+	}
+
+	// Область текста
+	area := size.Sub(image.Point{2 * c.padding, 2 * c.padding})
+	if icon != nil {
+		if c.placement == IconAbove {
+			area.Y -= iconSize.Y + iconGap
+		} else {
+			area.X -= iconSize.X + iconGap
+		}
+	}
+	area.X, area.Y = max(1, area.X), max(1, area.Y)
+
+	if icon == nil {
+		pos := image.Point{c.padding, c.padding}
+		drawAt(dst, c.drawText(area, pos.Y), pos)
+		return
+	}
+
+	// Иконка и текст центрируются вместе,
+	// поэтому область текста сужается до его размера
+	measure := text2img.MeasureSpans(c.textSpans(), c.font, area, c.layout)
+	box := area
+	if c.placement == IconAbove {
+		box.Y = min(box.Y, measure.Y)
+	} else {
+		box.X = min(box.X, measure.X)
+	}
+
+	var textPos, iconPos image.Point
+	switch c.placement {
+	case IconAbove:
+		y := (size.Y - iconSize.Y - iconGap - box.Y) / 2
+		iconPos = image.Point{(size.X - iconSize.X) / 2, y}
+		textPos = image.Point{c.padding, y + iconSize.Y + iconGap}
+
+	case IconRight:
+		x := (size.X - box.X - iconGap - iconSize.X) / 2
+		textPos = image.Point{x, c.padding}
+		iconPos = image.Point{x + box.X + iconGap, (size.Y - iconSize.Y) / 2}
+
+	default:
+		x := (size.X - iconSize.X - iconGap - box.X) / 2
+		iconPos = image.Point{x, (size.Y - iconSize.Y) / 2}
+		textPos = image.Point{x + iconSize.X + iconGap, c.padding}
+	}
+
+	drawAt(dst, icon, iconPos)
+	drawAt(dst, c.drawText(box, textPos.Y), textPos)
+}
+
+// Рисует текст в области размера size, расположенной на высоте top.
+// Базовая линия VAlignBaseline отсчитывается от верхнего края виджета,
+// поэтому тексты разных размеров в соседних виджетах стоят на одной линии
+func (c content) drawText(size image.Point, top int) *image.RGBA {
+	layout := c.layout
+	if layout.VAlign == text2img.VAlignBaseline && layout.Baseline != 0 {
+		layout.Baseline -= top
+	}
+	return text2img.DrawSpans(c.textSpans(), c.font, c.textColor, size, layout)
+}
+
+// Фрагменты текста. Простой текст - один фрагмент с оформлением виджета
+func (c content) textSpans() []text2img.Span {
+	if len(c.spans) > 0 {
+		return c.spans
+	}
+	return []text2img.Span{{Text: c.text}}
+}
+
+// Рисует изображение поверх dst в точке pos
+func drawAt(dst *image.RGBA, src image.Image, pos image.Point) {
+	b := src.Bounds()
+	r := image.Rectangle{Min: pos, Max: pos.Add(b.Size())}.Add(dst.Rect.Min)
+	draw.Draw(dst, r, src, b.Min, draw.Over)
+}
+
+// Возвращает изображение в формате RGBA с началом в точке 0,0
+func toRGBA(img image.Image) *image.RGBA {
+	if rgba, ok := img.(*image.RGBA); ok && rgba.Rect.Min == (image.Point{}) {
+		return rgba
+	}
+	b := img.Bounds()
+	out := image.NewRGBA(image.Rectangle{Max: b.Size()})
+	draw.Draw(out, out.Rect, img, b.Min, draw.Src)
+	return out
+}
@@ -0,0 +1,192 @@
+package widget
+
+import (
+	"image"
+	"image/color"
+	"os"
+
+	"github.com/anatolypaw/sgui/painter"
+)
+
+// Количество рендеров, хранимых виджетом Image.
+// Рендеры нужны, когда источник переключает несколько изображений
+const imageCacheSize = 8
+
+// Картинка: схема, логотип, фото.
+// Изображение вписывается в размер виджета способом mode
+// и масштабируется один раз, рендеры хранятся до смены размера или способа
+type Image struct {
+	size image.Point
+	mode painter.ScaleMode
+	back color.Color // Цвет областей, не закрытых изображением
+
+	img     image.Image
+	renders map[image.Image]*image.RGBA // Рендеры по исходным изображениям
+
+	// Если функция передана, то изображение берется из нее перед рендерингом.
+	// Изображение перемасштабируется, только если функция вернула
+	// изображение, которого еще нет в кэше
+	ImageSource func() image.Image
+
+	// Таймеры и анимации по часам кадров
+	clocked
+
+	hidden         bool
+	disabled       bool
+	disabledRender disabledCache // Рендер отключенного виджета
+
+	// Флаг, что изображение изменилось.
+	// Сбрасывется после рендеринга
+	updated bool
+}
+
+// Создает виджет с изображением img
+func NewImage(size image.Point, img image.Image, mode painter.ScaleMode) *Image {
+	return &Image{
+		size:    size,
+		mode:    mode,
+		img:     img,
+		renders: make(map[image.Image]*image.RGBA),
+		updated: true,
+	}
+}
+
+// Создает виджет с изображением PNG, JPEG или GIF
+func NewImageFromData(size image.Point, data []byte, mode painter.ScaleMode) (*Image, error) {
+	img, err := painter.DecodeImage(data)
+	if err != nil {
+		return nil, err
+	}
+	return NewImage(size, img, mode), nil
+}
+
+// Создает виджет с изображением из файла PNG, JPEG или GIF
+func NewImageFromFile(size image.Point, path string, mode painter.ScaleMode) (*Image, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return NewImageFromData(size, data, mode)
+}
+
+// Устанавливает изображение
+func (w *Image) SetImage(img image.Image) {
+	if w.img == img {
+		return
+	}
+	w.img = img
+	w.updated = true
+}
+
+// Устанавливает способ вписывания изображения
+func (w *Image) SetMode(mode painter.ScaleMode) {
+	if w.mode == mode {
+		return
+	}
+	w.mode = mode
+	w.clearCache()
+}
+
+// Устанавливает цвет областей, не закрытых изображением.
+// Если nil, то они прозрачные
+func (w *Image) SetBackground(c color.Color) {
+	if w.back == c {
+		return
+	}
+	w.back = c
+	w.clearCache()
+}
+
+// Устанавливает размер виджета
+func (w *Image) SetSize(size image.Point) {
+	if w.size == size {
+		return
+	}
+	w.size = size
+	w.clearCache()
+}
+
+// Удаляет все рендеры
+func (w *Image) clearCache() {
+	w.renders = make(map[image.Image]*image.RGBA)
+	w.updated = true
+}
+
+func (w *Image) Render() *image.RGBA {
+	w.updated = false
+
+	render, ok := w.renders[w.img]
+	if !ok {
+		if len(w.renders) >= imageCacheSize {
+			w.renders = make(map[image.Image]*image.RGBA)
+		}
+
+		if w.img == nil {
+			render = image.NewRGBA(image.Rectangle{Max: w.size})
+		} else {
+			render = painter.ScaleImage(w.img, w.size, w.mode, w.back)
+		}
+		w.renders[w.img] = render
+	}
+
+	if w.disabled {
+		return w.disabledRender.get(render)
+	}
+	return render
+}
+
+func (w *Image) Update() {
+	if w.ImageSource != nil {
+		w.SetImage(w.ImageSource())
+	}
+}
+
+func (w *Image) Size() image.Point {
+	return w.size
+}
+
+func (w *Image) Updated() bool {
+	return w.updated
+}
+
+func (w *Image) Tap(pos image.Point) {
+}
+
+func (w *Image) Release(pos image.Point) {
+}
+
+func (w *Image) Hide() {
+	w.hidden = true
+}
+
+func (w *Image) Show() {
+	if !w.hidden {
+		return
+	}
+	w.hidden = false
+	w.updated = true
+}
+
+func (w *Image) Hidden() bool {
+	return w.hidden
+}
+
+func (w *Image) Enable() {
+	if !w.disabled {
+		return
+	}
+	w.disabled = false
+	w.updated = true
+}
+
+func (w *Image) Disable() {
+	if w.disabled {
+		return
+	}
+	w.disabled = true
+	w.updated = true
+}
+
+func (w *Image) Disabled() bool {
+	return w.disabled
+}
@@ -4,6 +4,7 @@ import (
 	"image"
 	"image/color"
 	"log/slog"
+	"time"
 
 	"github.com/anatolypaw/sgui/painter"
 )
@@ -15,14 +16,33 @@ import (
 // 2) Для изменения состояния испольузется SetState()
 
 type bitIndicatorState struct {
-	img *image.RGBA
+	fill    color.Color // Цвет состояния
+	altFill color.Color // Цвет фазы off мигающего состояния из AddBlinkState
+
+	// Состояние, изображение которого показывается в фазе off,
+	// если мигание задано через SetBlink (blink.alt == nil)
+	altState int
+
+	// Если true, то цвет состояния берется из темы для состояния themeState
+	fromTheme  bool
+	themeState State
+
+	img   *image.RGBA
+	blink blink
 }
 
 type BitIndicator struct {
 	size         int
 	currentState int // Текущее состояние
 	states       []bitIndicatorState
-	theme        ColorTheme
+	colors       ColorTheme
+
+	// Если задан класс, то обводка и цвета состояний из темы
+	// берутся из текущей темы
+	theme themeBinding
+
+	// Таймеры и анимации по часам кадров
+	clocked
 
 	// Если эта функция указана, то она выполняется перед рендерингом
 	// Предназначена для получения состояния индикатора
@@ -34,6 +54,11 @@ type BitIndicator struct {
 	// Флаг, что изображение изменилось.
 	// Сбрасывется после рендеринга
 	updated bool
+
+	blinkPhase bool // Фаза мигания, показанная последним рендером
+
+	disabledRender *image.RGBA // Рендер отключенного индикатора
+	disabledState  int         // Состояние, из которого получен disabledRender
 }
 
 func NewIndicator(size int, stateLoader func() int, theme ColorTheme) *BitIndicator {
@@ -44,20 +69,122 @@ func NewIndicator(size int, stateLoader func() int, theme ColorTheme) *BitIndica
 		size:        size,
 		stateLoader: stateLoader,
 		updated:     true,
-		theme:       theme,
+		colors:      theme,
 	}
 }
 
+// Создает индикатор, оформленный текущей темой (класс ClassIndicator).
+// При смене темы состояния перерисовываются
+func NewThemedIndicator(size int, stateLoader func() int) *BitIndicator {
+	w := NewIndicator(size, stateLoader, ColorTheme{})
+	w.theme.bind(ClassIndicator)
+	return w
+}
+
 func (w *BitIndicator) AddState(c color.Color) {
+	w.states = append(w.states, bitIndicatorState{
+		fill: c,
+		img:  w.drawCircle(c),
+	})
+}
+
+// Добавляет состояние с цветом заливки из стиля темы для состояния s,
+// например StateAlarm
+func (w *BitIndicator) AddThemeState(s State) {
+	c := CurrentTheme().Style(w.themeClass(), s).FillColor
+	w.states = append(w.states, bitIndicatorState{
+		fill:       c,
+		fromTheme:  true,
+		themeState: s,
+		img:        w.drawCircle(c),
+	})
+}
+
+// Добавляет мигающее состояние.
+// В течение on отображается цвет c, в течение off - цвет alt.
+// Если alt nil, то в фазе off отображается только обводка
+func (w *BitIndicator) AddBlinkState(c color.Color, alt color.Color, on, off time.Duration) {
+	w.AddState(c)
+	state := &w.states[len(w.states)-1]
+	state.altFill = alt
+	state.blink = blink{
+		alt: w.drawCircle(alt),
+		on:  on,
+		off: off,
+	}
+}
+
+// Делает состояние state мигающим: в течение on отображается само состояние,
+// в течение off - изображение состояния altState.
+// Если on == 0, то мигание отключается
+func (w *BitIndicator) SetBlink(state int, altState int, on, off time.Duration) {
+	if state < 0 || state >= len(w.states) ||
+		altState < 0 || altState >= len(w.states) {
+		return
+	}
+
+	// Изображение состояния altState берется при отрисовке,
+	// так как его цвет может измениться вместе с темой
+	w.states[state].altState = altState
+	w.states[state].blink = blink{
+		on:  on,
+		off: off,
+	}
+	w.updated = true
+}
+
+// Класс темы индикатора. Для индикаторов без темы - ClassIndicator
+func (w *BitIndicator) themeClass() string {
+	if w.theme.themed() {
+		return w.theme.class
+	}
+	return ClassIndicator
+}
+
+// Рисует круг состояния с заливкой c
+func (w *BitIndicator) drawCircle(c color.Color) *image.RGBA {
 	circle := painter.Circle{
 		Radius:      w.size / 2,
 		FillColor:   c,
-		BackColor:   w.theme.BackgroundColor,
-		StrokeWidth: w.theme.StrokeWidth,
-		StrokeColor: w.theme.StrokeColor,
+		BackColor:   w.colors.BackgroundColor,
+		StrokeWidth: w.colors.StrokeWidth,
+		StrokeColor: w.colors.StrokeColor,
 	}
-	img := painter.DrawCircle(circle)
-	w.states = append(w.states, bitIndicatorState{img: img})
+
+	if w.theme.themed() {
+		style := w.theme.style(StateNormal)
+		circle.BackColor = nil
+		circle.StrokeWidth = style.StrokeWidth
+		circle.StrokeColor = style.StrokeColor
+	}
+
+	return painter.DrawCircle(circle)
+}
+
+// Рисует отключенный индикатор.
+// Если в теме задан стиль StateDisabled, то круг заливается его цветом,
+// иначе используется приглушенное изображение текущего состояния
+func (w *BitIndicator) drawDisabled() *image.RGBA {
+	if style, ok := w.theme.disabledStyle(); ok {
+		return w.drawCircle(style.FillColor)
+	}
+	return disabledImage(w.states[w.currentState].img)
+}
+
+// Перерисовывает все состояния, например после смены темы
+func (w *BitIndicator) redrawStates() {
+	w.disabledRender = nil
+	for i := range w.states {
+		state := &w.states[i]
+		if state.fromTheme {
+			state.fill = CurrentTheme().Style(w.themeClass(), state.themeState).FillColor
+		}
+		state.img = w.drawCircle(state.fill)
+		if state.blink.alt != nil {
+			state.blink.alt = w.drawCircle(state.altFill)
+		}
+	}
+	w.updated = true
 }
 
 func (w *BitIndicator) SetState(s int) {
@@ -93,7 +220,25 @@ func (w *BitIndicator) Render() *image.RGBA {
 	}
 
 	w.updated = false
-	return w.states[w.currentState].img
+
+	// Отключенный индикатор не мигает
+	if w.disabled {
+		if w.disabledRender == nil || w.disabledState != w.currentState {
+			w.disabledRender = w.drawDisabled()
+			w.disabledState = w.currentState
+		}
+		return w.disabledRender
+	}
+
+	state := w.states[w.currentState]
+	w.blinkPhase = state.blink.phase(&w.timers)
+	if !w.blinkPhase {
+		if state.blink.alt == nil {
+			return w.states[state.altState].img
+		}
+		return state.blink.alt
+	}
+	return state.img
 }
 
 func (w *BitIndicator) Tap(pos image.Point) {
@@ -122,6 +267,24 @@ func (w *BitIndicator) Show() {
 
 }
 
+// Включает индикатор
+func (w *BitIndicator) Enable() {
+	if !w.disabled {
+		return
+	}
+	w.disabled = false
+	w.updated = true
+}
+
+// Отключает индикатор, он отображается приглушенным
+func (w *BitIndicator) Disable() {
+	if w.disabled {
+		return
+	}
+	w.disabled = true
+	w.updated = true
+}
+
 func (w *BitIndicator) Disabled() bool {
 	return w.disabled
 }
@@ -131,8 +294,19 @@ func (w *BitIndicator) Hidden() bool {
 }
 
 func (w *BitIndicator) Update() {
+	// Сменилась тема, перерисовываем состояния
+	if w.theme.changed() {
+		w.redrawStates()
+	}
+
 	// Получаем статус индикатора с внешней функции
 	if w.stateLoader != nil {
 		w.SetState(w.stateLoader())
 	}
+
+	// Сменилась фаза мигания
+	if !w.disabled && w.currentState < len(w.states) &&
+		w.states[w.currentState].blink.phase(&w.timers) != w.blinkPhase {
+		w.updated = true
+	}
 }
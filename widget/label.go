@@ -4,6 +4,7 @@ import (
 	"image"
 	"image/color"
 	"image/draw"
+	"reflect"
 
 	"github.com/anatolypaw/sgui/painter"
 	"github.com/anatolypaw/sgui/text2img"
@@ -15,20 +16,29 @@ type Label struct {
 	// Флаг, что изображение изменилось.
 	// Сбрасывется после рендеринга
 	sizeUpdated    bool // размер виджета был изменен
-	textUpdated    bool // текст был изменен
+	backUpdated    bool // цвет заднего фона был изменен
+	textUpdated    bool // текст или иконка были изменены
 	baseUpdated    bool // основа была изменена
-	visibleUpdated bool // Изменена видимость виджета
+	visibleUpdated bool // Изменена видимость или доступность виджета
 
-	textRender       *image.RGBA // Рендер текста
-	baseRender       *image.RGBA // Рендер основы надписи (заливка, рамка, скругление)
-	finalRender      *image.RGBA // Рендер текста на основе
-	backgroundRender *image.RGBA // используется, когда виджет скрыт
+	baseRender  *image.RGBA // Рендер основы надписи (заливка, рамка, скругление)
+	finalRender *image.RGBA // Рендер текста на основе
+
+	disabled       bool
+	disabledRender *image.RGBA // Рендер отключенного виджета
+	disabledStale  bool        // Рендер отключенного виджета нужно перерисовать
 
 	// Если фнкция была передана, то она будет выполняться
 	// каждый раз перед рендерингом.
 	// если какой то из новых полученных параметров будет отличаться от текущих,
 	// то он будет применен
 	ParamSource func() LabelParam
+
+	// Если задан класс, то оформление берется из текущей темы
+	theme themeBinding
+
+	// Таймеры и анимации по часам кадров
+	clocked
 }
 
 // Ловить все события
@@ -47,6 +57,24 @@ type LabelParam struct {
 	StrokeWidth     float64
 	StrokeColor     color.Color
 	Hidden          bool
+
+	// Семейство и насыщенность шрифта, размер задает TextSize
+	Font text2img.Font
+
+	// Перенос, выравнивание и межстрочный интервал текста.
+	// По умолчанию одна строка по середине
+	TextLayout text2img.Layout
+
+	// Фрагменты текста со своим шрифтом, цветом и фоном.
+	// Если заданы, то рисуются вместо Text, незаданные поля
+	// фрагментов берутся из Font, TextSize и TextColor
+	Spans []text2img.Span
+
+	// Иконка рядом с текстом.
+	// Если IconTint, то иконка окрашивается в цвет текста
+	Icon          image.Image
+	IconPlacement IconPlacement
+	IconTint      bool
 }
 
 // Должен быть передан хотя бы один параметр
@@ -71,8 +99,48 @@ func NewLabel(p *LabelParam, ps func() LabelParam) *Label {
 	return &label
 }
 
+// Создает надпись, оформленную текущей темой (класс ClassLabel).
+// При смене темы надпись перерисовывается
+func NewThemedLabel(size image.Point, text string) *Label {
+	label := Label{}
+	label.theme.bind(ClassLabel)
+	label.SetParam(LabelParam{
+		Size: size,
+		Text: text,
+	})
+	label.Render()
+
+	return &label
+}
+
+// Устанавливает класс оформления из темы, например "label.title".
+// Пустой класс отключает оформление темой, текущие параметры сохраняются
+func (w *Label) SetClass(class string) {
+	w.theme.bind(class)
+	w.SetParam(w.param)
+}
+
+// Заменяет параметры оформления параметрами из темы
+func (w *Label) applyTheme(p LabelParam) LabelParam {
+	normal := w.theme.style(StateNormal)
+
+	p.FillColor = normal.FillColor
+	p.CornerRadius = normal.CornerRadius
+	p.StrokeWidth = normal.StrokeWidth
+	p.StrokeColor = normal.StrokeColor
+	p.TextColor = normal.TextColor
+	p.TextSize = normal.TextSize
+	p.Font = normal.Font
+	return p
+}
+
 // Установка параметров виджета
+// Если надпись оформлена темой, то параметры оформления берутся из темы
 func (w *Label) SetParam(p LabelParam) {
+	if w.theme.themed() {
+		p = w.applyTheme(p)
+	}
+
 	if w.param.Hidden != p.Hidden {
 		w.param.Hidden = p.Hidden
 		w.visibleUpdated = true
@@ -82,6 +150,11 @@ func (w *Label) SetParam(p LabelParam) {
 	w.SetBackground(p.BackgroundColor)
 	w.SetBase(p.FillColor, p.CornerRadius, p.StrokeWidth, p.StrokeColor)
 	w.SetText(p.Text, p.TextSize, p.TextColor)
+	w.SetFont(p.Font)
+	w.SetTextLayout(p.TextLayout)
+	w.SetSpans(p.Spans)
+	w.SetIcon(p.Icon)
+	w.SetIconPlacement(p.IconPlacement, p.IconTint)
 }
 
 // Установить размер
@@ -94,26 +167,17 @@ func (w *Label) SetSize(size image.Point) {
 	w.sizeUpdated = true
 }
 
-// Установить задний фон
+// Установить цвет заднего фона под скругленными углами.
+// Если nil, то углы прозрачные и под надписью виден экран
 func (w *Label) SetBackground(c color.Color) {
-	// Создаем background для скрытого состояния
-
-	// Цвет и размер не изменился, пропускаем
-	if w.param.BackgroundColor == c &&
-		!w.sizeUpdated {
+	// Цвет не изменился, пропускаем
+	if w.param.BackgroundColor == c {
 		return
 	}
 
-	// Обновляем параметры
+	// Обновляем параметры, основа будет перерисована
 	w.param.BackgroundColor = c
-
-	w.backgroundRender = painter.DrawRectangle(
-		painter.Rectangle{
-			Size:      w.param.Size,
-			BackColor: w.param.BackgroundColor,
-		},
-	)
-
+	w.backUpdated = true
 }
 
 // Установить основу надписи (подложку)
@@ -129,7 +193,8 @@ func (w *Label) SetBase(
 		w.param.CornerRadius == cornerRadius &&
 		w.param.StrokeWidth == strokeWidth &&
 		w.param.StrokeColor == strokeColor &&
-		!w.sizeUpdated {
+		!w.sizeUpdated &&
+		!w.backUpdated {
 		return
 	}
 
@@ -155,6 +220,11 @@ func (w *Label) SetBase(
 
 }
 
+// Установить новый текст, сохранив размер и цвет текста
+func (w *Label) SetCaption(text string) {
+	w.SetText(text, w.param.TextSize, w.param.TextColor)
+}
+
 // Установить новый текст
 func (w *Label) SetText(text string, size float64, color color.Color) {
 	// Проверяем, отличаются ли новые параметры от сущесвубщих
@@ -166,21 +236,86 @@ func (w *Label) SetText(text string, size float64, color color.Color) {
 		return
 	}
 
-	// Обновляем параметры
+	// Обновляем параметры, текст будет отрисован при рендеринге
 	w.param.Text = text
 	w.param.TextSize = size
 	w.param.TextColor = color
+	w.textUpdated = true
+}
 
-	// Создаем рендер текста и вычисляем его расположение
-	// для размещения в середине виджета
-	w.textRender = text2img.Text2img(
-		w.param.Text,
-		w.param.TextSize,
-		w.param.TextColor,
-	)
+// Установить фрагменты текста. nil возвращает простой текст
+func (w *Label) SetSpans(spans []text2img.Span) {
+	if reflect.DeepEqual(w.param.Spans, spans) {
+		return
+	}
+	w.param.Spans = spans
+	w.textUpdated = true
+}
+
+// Установить текст с разметкой, например "Температура: **82.4** °C"
+// или "[color=#c00000]Авария[/color] насоса", см. text2img.ParseMarkup
+func (w *Label) SetMarkup(markup string) error {
+	spans, err := text2img.ParseMarkup(markup)
+	if err != nil {
+		return err
+	}
+	w.SetSpans(spans)
+	return nil
+}
+
+// Установить перенос, выравнивание и межстрочный интервал текста
+func (w *Label) SetTextLayout(l text2img.Layout) {
+	if w.param.TextLayout == l {
+		return
+	}
+	w.param.TextLayout = l
+	w.textUpdated = true
+}
+
+// Установить семейство и насыщенность шрифта
+func (w *Label) SetFont(font text2img.Font) {
+	if w.param.Font == font {
+		return
+	}
+	w.param.Font = font
+	w.textUpdated = true
+}
+
+// Установить иконку. nil убирает иконку
+func (w *Label) SetIcon(icon image.Image) {
+	if w.param.Icon == icon {
+		return
+	}
+	w.param.Icon = icon
+	w.textUpdated = true
+}
+
+// Установить расположение иконки относительно текста
+// и окрашивание ее в цвет текста
+func (w *Label) SetIconPlacement(placement IconPlacement, tint bool) {
+	if w.param.IconPlacement == placement && w.param.IconTint == tint {
+		return
+	}
+	w.param.IconPlacement = placement
+	w.param.IconTint = tint
 	w.textUpdated = true
 }
 
+// Содержимое надписи с цветом текста textColor
+func (w *Label) content(textColor color.Color) content {
+	return content{
+		text:      w.param.Text,
+		spans:     w.param.Spans,
+		font:      textFont(w.param.Font, w.param.TextSize),
+		textColor: textColor,
+		layout:    w.param.TextLayout,
+		padding:   contentPadding(w.param.StrokeWidth),
+		icon:      w.param.Icon,
+		placement: w.param.IconPlacement,
+		tint:      w.param.IconTint,
+	}
+}
+
 // Отобразить виджет
 func (w *Label) Show() {
 	w.param.Hidden = false
@@ -203,6 +338,15 @@ func (w *Label) Update() {
 		param := w.ParamSource()
 		w.SetParam(param)
 	}
+
+	// Сменилась тема, применяем новое оформление
+	if w.theme.changed() {
+		w.SetParam(w.param)
+		w.disabledStale = true
+		if w.disabled {
+			w.visibleUpdated = true
+		}
+	}
 }
 
 // Render implements sgui.IWidget.
@@ -219,6 +363,8 @@ func (w *Label) Render() *image.RGBA {
 	// Композиция слоев
 	// Был изменен текст или основа
 	if w.textUpdated || w.baseUpdated {
+		w.disabledStale = true
+
 		// Рисуем основу
 		draw.Draw(w.finalRender,
 			w.finalRender.Bounds(),
@@ -226,32 +372,56 @@ func (w *Label) Render() *image.RGBA {
 			image.Point{0, 0},
 			draw.Src)
 
-		// Рисуем текст
-		textMidPos := image.Point{
-			X: -(w.param.Size.X - w.textRender.Rect.Dx()) / 2,
-			Y: -(w.param.Size.Y-w.textRender.Rect.Dy())/2 - w.textRender.Rect.Dy()/12,
-		}
-
-		draw.Draw(w.finalRender,
-			w.finalRender.Bounds(),
-			w.textRender,
-			textMidPos,
-			draw.Over)
+		// Рисуем текст и иконку
+		drawContent(w.finalRender, w.content(w.param.TextColor))
 
 		w.textUpdated = false
 	}
 
 	w.baseUpdated = false
 	w.textUpdated = false
-
-	// Виджет скрыт
-	if w.param.Hidden {
-		return w.backgroundRender
+	w.backUpdated = false
+	w.visibleUpdated = false
+
+	// Рендер отключенного виджета
+	if w.disabled {
+		if w.disabledStale || w.disabledRender == nil {
+			w.disabledRender = w.drawDisabled()
+			w.disabledStale = false
+		}
+		return w.disabledRender
 	}
 
 	return w.finalRender
 }
 
+// Рисует отключенную надпись.
+// Если в теме задан стиль StateDisabled, то надпись рисуется им,
+// иначе используется приглушенный основной рендер
+func (w *Label) drawDisabled() *image.RGBA {
+	style, ok := w.theme.disabledStyle()
+	if !ok {
+		return disabledImage(w.finalRender)
+	}
+
+	img := painter.DrawRectangle(
+		painter.Rectangle{
+			Size:         w.param.Size,
+			FillColor:    style.FillColor,
+			BackColor:    w.param.BackgroundColor,
+			CornerRadius: style.CornerRadius,
+			StrokeWidth:  style.StrokeWidth,
+			StrokeColor:  style.StrokeColor,
+		},
+	)
+
+	c := w.content(style.TextColor)
+	c.gray = true
+	drawContent(img, c)
+
+	return img
+}
+
 // Возвращает размер виджета
 func (w *Label) Size() image.Point {
 	return w.param.Size
@@ -273,7 +443,25 @@ func (w *Label) Hidden() bool {
 	return w.param.Hidden
 }
 
-// Считаем что виджет отключен, когда скрыт
+// Включить виджет
+func (w *Label) Enable() {
+	if !w.disabled {
+		return
+	}
+	w.disabled = false
+	w.visibleUpdated = true
+}
+
+// Отключить виджет, он отображается приглушенным
+func (w *Label) Disable() {
+	if w.disabled {
+		return
+	}
+	w.disabled = true
+	w.visibleUpdated = true
+}
+
+// Возвращает, отключен ли виджет
 func (w *Label) Disabled() bool {
-	return w.param.Hidden
+	return w.disabled
 }
@@ -9,16 +9,38 @@ import (
 
 // Прямоугольник без действий залитый сплошным цветом
 type rectangle struct {
-	size       image.Point
-	render     *image.RGBA
-	background *image.RGBA
-	hidden     bool
-	updated    bool
+	size     image.Point
+	render   *image.RGBA
+	hidden   bool
+	disabled bool
+	updated  bool
+
+	disabledRender disabledCache // Рендер отключенного прямоугольника
+
+	clocked // Таймеры и анимации по часам кадров
+}
+
+// Enable implements sgui.IWidget.
+func (w *rectangle) Enable() {
+	if !w.disabled {
+		return
+	}
+	w.disabled = false
+	w.updated = true
+}
+
+// Disable implements sgui.IWidget.
+func (w *rectangle) Disable() {
+	if w.disabled {
+		return
+	}
+	w.disabled = true
+	w.updated = true
 }
 
 // Disabled implements sgui.IWidget.
 func (w *rectangle) Disabled() bool {
-	return false
+	return w.disabled
 }
 
 // Hidden implements sgui.IWidget.
@@ -48,6 +70,8 @@ func (w *rectangle) Updated() bool {
 	return w.updated
 }
 
+// Создает прямоугольник размера size, залитый цветом color.
+// background не используется и оставлен для совместимости
 func NewRectangle(size image.Point, color color.Color, background color.Color) *rectangle {
 	if size.X <= 0 {
 		size.X = 1
@@ -64,26 +88,18 @@ func NewRectangle(size image.Point, color color.Color, background color.Color) *
 		},
 	)
 
-	back := painter.DrawRectangle(
-		painter.Rectangle{
-			Size:      size,
-			FillColor: background,
-		},
-	)
-
 	return &rectangle{
-		size:       size,
-		render:     img,
-		background: back,
+		size:    size,
+		render:  img,
+		updated: true,
 	}
 }
 
 func (w *rectangle) Render() *image.RGBA {
 	w.updated = false
-	if w.hidden {
-		return w.background
+	if w.disabled {
+		return w.disabledRender.get(w.render)
 	}
-
 	return w.render
 }
 
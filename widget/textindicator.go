@@ -3,8 +3,8 @@ package widget
 import (
 	"image"
 	"image/color"
-	"image/draw"
 	"log/slog"
+	"time"
 
 	"github.com/anatolypaw/sgui/painter"
 	"github.com/anatolypaw/sgui/text2img"
@@ -18,22 +18,59 @@ type TextIndicatorParam struct {
 	CornerRadius    float64
 	StrokeWidth     float64
 
+	// Семейство и насыщенность шрифта надписей, размер задается в AddState
+	Font text2img.Font
+
+	// Перенос и выравнивание надписей. Для выравнивания надписей
+	// разного размера по одной линии используется VAlignBaseline,
+	// Baseline отсчитывается от верхнего края индикатора
+	TextLayout text2img.Layout
+
 	// Если эта функция указана, то данные берутся из нее
 	// Предназначена для получения состояния индикатора
 	StateSource func() int
 }
 
+type textIndicatorState struct {
+	text        string
+	textSize    float64
+	textColor   color.Color
+	fillColor   color.Color
+	strokeColor color.Color
+
+	// Если true, то цвета и размер текста берутся из темы
+	// для состояния themeState
+	fromTheme  bool
+	themeState State
+
+	img      *image.RGBA
+	blink    blink
+	altState int // Состояние, изображение которого показывается в фазе off
+}
+
 type TextIndicator struct {
 	param TextIndicatorParam
 
 	currentState int
-	states       []*image.RGBA
+	states       []textIndicatorState
 	hidden       bool
 	disabled     bool
 
+	// Если задан класс, то скругление и обводка
+	// берутся из текущей темы
+	theme themeBinding
+
+	// Таймеры и анимации по часам кадров
+	clocked
+
 	// Флаг, что изображение изменилось.
 	// Сбрасывется после рендеринга
 	updated bool
+
+	blinkPhase bool // Фаза мигания, показанная последним рендером
+
+	disabledRender *image.RGBA // Рендер отключенного индикатора
+	disabledState  int         // Состояние, из которого получен disabledRender
 }
 
 func NewTextIndicator(p TextIndicatorParam) *TextIndicator {
@@ -50,6 +87,17 @@ func NewTextIndicator(p TextIndicatorParam) *TextIndicator {
 	}
 }
 
+// Создает текстовый индикатор, оформленный текущей темой (класс ClassTextIndicator).
+// При смене темы состояния перерисовываются
+func NewThemedTextIndicator(size image.Point, stateSource func() int) *TextIndicator {
+	w := NewTextIndicator(TextIndicatorParam{
+		Size:        size,
+		StateSource: stateSource,
+	})
+	w.theme.bind(ClassTextIndicator)
+	return w
+}
+
 func (w *TextIndicator) AddState(
 	text string,
 	textSize float64,
@@ -57,35 +105,117 @@ func (w *TextIndicator) AddState(
 	fillColor color.Color,
 	strokeColor color.Color) {
 
-	// Создаем рендер основы надписи
-	baseRender := painter.DrawRectangle(
-		painter.Rectangle{
-			Size:         w.param.Size,
-			FillColor:    fillColor,
-			BackColor:    w.param.BackgroundColor,
-			CornerRadius: w.param.CornerRadius,
-			StrokeWidth:  w.param.StrokeWidth,
-			StrokeColor:  strokeColor,
-		},
-	)
-
-	// Создаем рендер текста и вычисляем его расположение
-	// для размещения в середине виджета
-	textRender := text2img.Text2img(text, textSize, textColor)
-	textMidPos := image.Point{
-		X: -(w.param.Size.X - textRender.Rect.Dx()) / 2,
-		Y: -(w.param.Size.Y-textRender.Rect.Dy())/2 - textRender.Rect.Dy()/12,
-	}
-
-	// Добавляем текст в базовый ренден
-	draw.Draw(baseRender,
-		baseRender.Bounds(),
-		textRender,
-		textMidPos,
-		draw.Over)
+	state := textIndicatorState{
+		text:        text,
+		textSize:    textSize,
+		textColor:   textColor,
+		fillColor:   fillColor,
+		strokeColor: strokeColor,
+	}
+	state.img = w.drawState(state)
 
 	// Добавляем рендер в состояния
-	w.states = append(w.states, baseRender)
+	w.states = append(w.states, state)
+}
+
+// Добавляет состояние с надписью text, оформленное стилем темы
+// для состояния s, например StateAlarm
+func (w *TextIndicator) AddThemeState(text string, s State) {
+	state := textIndicatorState{
+		text:       text,
+		fromTheme:  true,
+		themeState: s,
+	}
+	state.img = w.drawState(state)
+	w.states = append(w.states, state)
+}
+
+// Рисует изображение состояния
+func (w *TextIndicator) drawState(s textIndicatorState) *image.RGBA {
+	rect := painter.Rectangle{
+		Size:         w.param.Size,
+		FillColor:    s.fillColor,
+		BackColor:    w.param.BackgroundColor,
+		CornerRadius: w.param.CornerRadius,
+		StrokeWidth:  w.param.StrokeWidth,
+		StrokeColor:  s.strokeColor,
+	}
+
+	font := w.param.Font
+	if w.theme.themed() {
+		normal := w.theme.style(StateNormal)
+		font = normal.Font
+		rect.BackColor = nil
+		rect.CornerRadius = normal.CornerRadius
+		rect.StrokeWidth = normal.StrokeWidth
+	}
+
+	if s.fromTheme {
+		class := ClassTextIndicator
+		if w.theme.themed() {
+			class = w.theme.class
+		}
+		style := CurrentTheme().Style(class, s.themeState)
+		s.textSize = style.TextSize
+		font = style.Font
+		s.textColor = style.TextColor
+		rect.FillColor = style.FillColor
+		rect.StrokeColor = style.StrokeColor
+	}
+
+	// Создаем рендер основы надписи и добавляем в него текст
+	baseRender := painter.DrawRectangle(rect)
+	drawContent(baseRender, content{
+		text:      s.text,
+		font:      textFont(font, s.textSize),
+		textColor: s.textColor,
+		layout:    w.param.TextLayout,
+		padding:   contentPadding(rect.StrokeWidth),
+	})
+
+	return baseRender
+}
+
+// Рисует отключенный индикатор.
+// Если в теме задан стиль StateDisabled, то надпись текущего состояния
+// рисуется им, иначе используется приглушенное изображение текущего состояния
+func (w *TextIndicator) drawDisabled() *image.RGBA {
+	if _, ok := w.theme.disabledStyle(); ok {
+		return w.drawState(textIndicatorState{
+			text:       w.states[w.currentState].text,
+			fromTheme:  true,
+			themeState: StateDisabled,
+		})
+	}
+	return disabledImage(w.states[w.currentState].img)
+}
+
+// Перерисовывает все состояния, например после смены темы
+func (w *TextIndicator) redrawStates() {
+	w.disabledRender = nil
+	for i := range w.states {
+		w.states[i].img = w.drawState(w.states[i])
+	}
+	w.updated = true
+}
+
+// Делает состояние state мигающим: в течение on отображается само состояние,
+// в течение off - изображение состояния altState.
+// Если on == 0, то мигание отключается
+func (w *TextIndicator) SetBlink(state int, altState int, on, off time.Duration) {
+	if state < 0 || state >= len(w.states) ||
+		altState < 0 || altState >= len(w.states) {
+		return
+	}
+
+	// Изображение состояния altState берется при отрисовке,
+	// так как оно может быть перерисовано, например при смене темы
+	w.states[state].altState = altState
+	w.states[state].blink = blink{
+		on:  on,
+		off: off,
+	}
+	w.updated = true
 }
 
 func (w *TextIndicator) SetState(s int) {
@@ -123,16 +253,26 @@ func (w *TextIndicator) Render() *image.RGBA {
 			color.Black,
 			nil,
 		)
-		slog.Error("No states for BitIndicator. Created empty state")
+		slog.Error("No states for TextIndicator. Created empty state")
 	}
 
-	// Получаем статус индикатора с внешней функции
-	if w.param.StateSource != nil {
-		w.SetState(w.param.StateSource())
+	w.updated = false
+
+	// Отключенный индикатор не мигает
+	if w.disabled {
+		if w.disabledRender == nil || w.disabledState != w.currentState {
+			w.disabledRender = w.drawDisabled()
+			w.disabledState = w.currentState
+		}
+		return w.disabledRender
 	}
 
-	w.updated = false
-	return w.states[w.currentState]
+	state := w.states[w.currentState]
+	w.blinkPhase = state.blink.phase(&w.timers)
+	if !w.blinkPhase {
+		return w.states[state.altState].img
+	}
+	return state.img
 }
 
 func (w *TextIndicator) Tap(pos image.Point) {
@@ -161,6 +301,24 @@ func (w *TextIndicator) Show() {
 
 }
 
+// Включает индикатор
+func (w *TextIndicator) Enable() {
+	if !w.disabled {
+		return
+	}
+	w.disabled = false
+	w.updated = true
+}
+
+// Отключает индикатор, он отображается приглушенным
+func (w *TextIndicator) Disable() {
+	if w.disabled {
+		return
+	}
+	w.disabled = true
+	w.updated = true
+}
+
 func (w *TextIndicator) Disabled() bool {
 	return w.disabled
 }
@@ -169,4 +327,20 @@ func (w *TextIndicator) Hidden() bool {
 	return w.hidden
 }
 
-func (w *TextIndicator) Update() {}
+func (w *TextIndicator) Update() {
+	// Сменилась тема, перерисовываем состояния
+	if w.theme.changed() {
+		w.redrawStates()
+	}
+
+	// Получаем статус индикатора с внешней функции
+	if w.param.StateSource != nil {
+		w.SetState(w.param.StateSource())
+	}
+
+	// Сменилась фаза мигания
+	if !w.disabled && w.currentState < len(w.states) &&
+		w.states[w.currentState].blink.phase(&w.timers) != w.blinkPhase {
+		w.updated = true
+	}
+}
@@ -0,0 +1,283 @@
+package widget
+
+import (
+	"image/color"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/anatolypaw/sgui/painter"
+	"github.com/anatolypaw/sgui/text2img"
+)
+
+// Состояние виджета, для которого в теме задается стиль
+type State int
+
+const (
+	StateNormal State = iota
+	StatePressed
+	StateDisabled
+	StateFocused // Виджет в фокусе, например при управлении энкодером
+	StateAlarm
+	StateChecked // Включенное состояние переключателя
+)
+
+// Классы виджетов в теме.
+// Можно задавать собственные классы через точку, например "button.danger".
+// Если для такого класса стиль не задан, то берется стиль базового класса
+const (
+	ClassButton        = "button"
+	ClassLabel         = "label"
+	ClassIndicator     = "indicator"
+	ClassTextIndicator = "textindicator"
+)
+
+// Оформление виджета в одном состоянии
+type Style struct {
+	FillColor    color.Color
+	StrokeColor  color.Color
+	StrokeWidth  float64
+	CornerRadius float64
+	TextColor    color.Color
+	TextSize     float64
+	Font         text2img.Font // Семейство и насыщенность шрифта, размер задает TextSize
+
+	// Градиент, тени и фаска основы
+	Effects painter.Effects
+}
+
+// Тема оформления: стили для классов виджетов и их состояний
+type Theme struct {
+	Name       string
+	Background color.Color // Цвет фона экранов
+
+	mu     sync.RWMutex
+	styles map[string]map[State]Style
+}
+
+// Создает пустую тему
+func NewTheme(name string, background color.Color) *Theme {
+	return &Theme{
+		Name:       name,
+		Background: background,
+		styles:     make(map[string]map[State]Style),
+	}
+}
+
+// Создает тему со стилями всех классов виджетов из набора цветов
+func ThemeFromColors(name string, ct ColorTheme) *Theme {
+	t := NewTheme(name, ct.BackgroundColor)
+
+	base := Style{
+		FillColor:    ct.MainColor,
+		StrokeColor:  ct.StrokeColor,
+		StrokeWidth:  ct.StrokeWidth,
+		CornerRadius: ct.CornerRadius,
+		TextColor:    ct.TextColor,
+		TextSize:     20,
+	}
+
+	pressed := base
+	pressed.FillColor = ct.SecondColor
+
+	alarm := base
+	alarm.FillColor = color.RGBA{220, 30, 30, 255}
+	alarm.TextColor = color.White
+
+	// Кнопка в фокусе выделяется цветом обводки
+	focused := base
+	focused.StrokeColor = color.RGBA{30, 110, 220, 255}
+
+	// Включенное состояние переключателя
+	checked := base
+	checked.FillColor = color.RGBA{40, 170, 70, 255}
+	checked.TextColor = color.White
+
+	// Отключенное состояние: серые цвета, текст близок к заливке
+	disabled := base
+	disabled.FillColor = fadeColor(ct.MainColor, DisabledFade)
+	disabled.StrokeColor = fadeColor(ct.StrokeColor, DisabledFade)
+	disabled.TextColor = fadeColor(ct.TextColor, DisabledFade)
+
+	t.SetStyle(ClassButton, StateNormal, base)
+	t.SetStyle(ClassButton, StatePressed, pressed)
+	t.SetStyle(ClassButton, StateDisabled, disabled)
+	t.SetStyle(ClassButton, StateFocused, focused)
+	t.SetStyle(ClassButton, StateAlarm, alarm)
+	t.SetStyle(ClassButton, StateChecked, checked)
+
+	label := base
+	label.FillColor = nil
+	label.StrokeWidth = 0
+	labelDisabled := label
+	labelDisabled.TextColor = disabled.TextColor
+	t.SetStyle(ClassLabel, StateNormal, label)
+	t.SetStyle(ClassLabel, StateDisabled, labelDisabled)
+	t.SetStyle(ClassLabel, StateAlarm, Style{TextColor: alarm.FillColor, TextSize: label.TextSize})
+
+	t.SetStyle(ClassIndicator, StateNormal, base)
+	t.SetStyle(ClassIndicator, StateAlarm, alarm)
+
+	t.SetStyle(ClassTextIndicator, StateNormal, base)
+	t.SetStyle(ClassTextIndicator, StateDisabled, disabled)
+	t.SetStyle(ClassTextIndicator, StateAlarm, alarm)
+
+	return t
+}
+
+// Задает стиль класса в состоянии.
+// Если тема текущая, то виджеты перерисуются при следующем обновлении
+func (t *Theme) SetStyle(class string, state State, s Style) {
+	t.mu.Lock()
+	if t.styles == nil {
+		t.styles = make(map[string]map[State]Style)
+	}
+	if t.styles[class] == nil {
+		t.styles[class] = make(map[State]Style)
+	}
+	t.styles[class][state] = s
+	t.mu.Unlock()
+
+	if t == CurrentTheme() {
+		themeVersion.Add(1)
+	}
+}
+
+// Возвращает стиль класса в состоянии.
+// Стиль ищется у класса, затем у базовых классов ("button" для "button.danger").
+// Если для состояния стиль нигде не задан, то возвращается стиль нормального состояния
+func (t *Theme) Style(class string, state State) Style {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	if s, ok := t.lookup(class, state); ok {
+		return s
+	}
+	s, _ := t.lookup(class, StateNormal)
+	return s
+}
+
+// Возвращает стиль класса в состоянии и true, если он задан в теме явно
+// для класса или его базового класса
+func (t *Theme) Lookup(class string, state State) (Style, bool) {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	return t.lookup(class, state)
+}
+
+func (t *Theme) lookup(class string, state State) (Style, bool) {
+	for {
+		if s, ok := t.styles[class][state]; ok {
+			return s, true
+		}
+
+		i := strings.LastIndex(class, ".")
+		if i < 0 {
+			return Style{}, false
+		}
+		class = class[:i]
+	}
+}
+
+// Текущая тема. Виджеты, созданные из темы, следят за ее сменой
+var (
+	themeMu        sync.RWMutex
+	currentTheme   *Theme
+	themeListeners []func(*Theme)
+	themeVersion   atomic.Uint64
+)
+
+// Тема по умолчанию, используется пока не вызван SetTheme()
+var DefaultTheme *Theme
+
+// Тема по умолчанию создается в init, так как SetStyle проверяет,
+// не является ли тема текущей
+func init() {
+	DefaultTheme = ThemeFromColors("default", ColorTheme{
+		BackgroundColor: color.RGBA{240, 240, 240, 255},
+		MainColor:       color.RGBA{200, 200, 200, 255},
+		SecondColor:     color.RGBA{180, 180, 180, 255},
+		TextColor:       color.Black,
+		StrokeColor:     color.RGBA{60, 60, 60, 255},
+		StrokeWidth:     2,
+		CornerRadius:    10,
+	})
+}
+
+// Устанавливает текущую тему.
+// Виджеты, созданные из темы, перерисуются при следующем обновлении
+func SetTheme(t *Theme) {
+	themeMu.Lock()
+	currentTheme = t
+	listeners := append([]func(*Theme){}, themeListeners...)
+	themeMu.Unlock()
+
+	themeVersion.Add(1)
+
+	for _, f := range listeners {
+		f(t)
+	}
+}
+
+// Возвращает текущую тему
+func CurrentTheme() *Theme {
+	themeMu.RLock()
+	defer themeMu.RUnlock()
+
+	if currentTheme == nil {
+		return DefaultTheme
+	}
+	return currentTheme
+}
+
+// Добавляет функцию, вызываемую при смене темы.
+// Например, для смены фона экранов
+func OnThemeChange(f func(*Theme)) {
+	themeMu.Lock()
+	defer themeMu.Unlock()
+	themeListeners = append(themeListeners, f)
+}
+
+// Привязка виджета к классу текущей темы
+type themeBinding struct {
+	class   string // Класс в теме. Пустой - виджет не оформляется темой
+	version uint64 // Версия темы, по которой оформлен виджет
+}
+
+// Возвращает true, если виджет оформляется темой
+func (b *themeBinding) themed() bool {
+	return b.class != ""
+}
+
+// Возвращает стиль класса виджета в состоянии
+func (b *themeBinding) style(state State) Style {
+	return CurrentTheme().Style(b.class, state)
+}
+
+// Возвращает стиль отключенного состояния, если виджет оформлен темой
+// и в ней задан такой стиль
+func (b *themeBinding) disabledStyle() (Style, bool) {
+	if !b.themed() {
+		return Style{}, false
+	}
+	return CurrentTheme().Lookup(b.class, StateDisabled)
+}
+
+// Возвращает true, если тема сменилась с последнего вызова
+func (b *themeBinding) changed() bool {
+	if !b.themed() {
+		return false
+	}
+	v := themeVersion.Load()
+	if v == b.version {
+		return false
+	}
+	b.version = v
+	return true
+}
+
+// Запоминает текущую версию темы
+func (b *themeBinding) bind(class string) {
+	b.class = class
+	b.version = themeVersion.Load()
+}
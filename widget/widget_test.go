@@ -1,12 +1,20 @@
-package widget
+package widget_test
 
 import (
-	"fmt"
+	"bytes"
+	"image"
 	"image/color"
-	"image/png"
-	"math/rand"
-	"os"
+	"image/draw"
+	"image/gif"
+	"sync/atomic"
 	"testing"
+	"time"
+
+	"github.com/anatolypaw/sgui/anim"
+	"github.com/anatolypaw/sgui/painter"
+	"github.com/anatolypaw/sgui/sguitest"
+	"github.com/anatolypaw/sgui/text2img"
+	"github.com/anatolypaw/sgui/widget"
 )
 
 func TestDrawCircle(t *testing.T) {
@@ -14,30 +22,716 @@ func TestDrawCircle(t *testing.T) {
 		name   string
 		radius int
 	}{
-		{"indicator", rand.Int() % 50},
+		{"indicator", 40},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 
-			indicator := NewIndicator(tt.radius, nil, ColorTheme{
+			indicator := widget.NewIndicator(tt.radius, nil, widget.ColorTheme{
 				BackgroundColor: color.White,
 				StrokeColor:     color.Black,
 				StrokeWidth:     2,
 			})
-			img := indicator.Render()
+			indicator.AddState(color.RGBA{0, 200, 0, 255})
+
+			sguitest.AssertGolden(t, tt.name, indicator.Render(), 1)
+		})
+	}
+}
+
+func TestIndicatorBlink(t *testing.T) {
+	start := time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC)
+	clock := anim.NewClock(start)
+
+	red := color.RGBA{255, 0, 0, 255}
+	grey := color.RGBA{80, 80, 80, 255}
+
+	newIndicator := func() *widget.BitIndicator {
+		ind := widget.NewIndicator(20, nil, widget.ColorTheme{})
+		ind.SetClock(clock)
+		ind.AddState(grey)
+		ind.AddBlinkState(red, grey, 250*time.Millisecond, 250*time.Millisecond)
+		return ind
+	}
+	a, b := newIndicator(), newIndicator()
+	a.SetState(1)
+
+	// Второй индикатор включает мигание позже, но фаза должна совпадать
+	clock.Tick(start.Add(100 * time.Millisecond))
+	b.SetState(1)
+
+	for _, tt := range []struct {
+		at   time.Duration
+		want color.RGBA
+	}{
+		{200 * time.Millisecond, red},
+		{300 * time.Millisecond, grey},
+		{600 * time.Millisecond, red},
+	} {
+		clock.Tick(start.Add(tt.at))
+		for name, ind := range map[string]*widget.BitIndicator{"a": a, "b": b} {
+			ind.Update()
+			if got := ind.Render().RGBAAt(10, 10); got != tt.want {
+				t.Errorf("%s at %v: color %v, want %v", name, tt.at, got, tt.want)
+			}
+		}
+	}
+
+	// В фазе off показывается текущий цвет состояния altState
+	blue := color.RGBA{0, 0, 255, 255}
+	day := widget.NewTheme("day", color.White)
+	day.SetStyle(widget.ClassIndicator, widget.StateNormal, widget.Style{FillColor: grey})
+	day.SetStyle(widget.ClassIndicator, widget.StateAlarm, widget.Style{FillColor: red})
+	night := widget.NewTheme("night", color.Black)
+	night.SetStyle(widget.ClassIndicator, widget.StateNormal, widget.Style{FillColor: blue})
+	night.SetStyle(widget.ClassIndicator, widget.StateAlarm, widget.Style{FillColor: red})
+	defer widget.SetTheme(nil)
+
+	widget.SetTheme(day)
+	themed := widget.NewThemedIndicator(20, nil)
+	themed.SetClock(clock)
+	themed.AddThemeState(widget.StateNormal)
+	themed.AddThemeState(widget.StateAlarm)
+	themed.SetBlink(1, 0, 250*time.Millisecond, 250*time.Millisecond)
+	themed.SetState(1)
+
+	widget.SetTheme(night)
+	clock.Tick(start.Add(800 * time.Millisecond))
+	themed.Update()
+	if got := themed.Render().RGBAAt(10, 10); got != blue {
+		t.Errorf("off phase after theme switch: %v, want %v", got, blue)
+	}
+
+	// То же для текстового индикатора
+	day.SetStyle(widget.ClassTextIndicator, widget.StateNormal, widget.Style{FillColor: grey})
+	day.SetStyle(widget.ClassTextIndicator, widget.StateAlarm, widget.Style{FillColor: red})
+	night.SetStyle(widget.ClassTextIndicator, widget.StateNormal, widget.Style{FillColor: blue})
+	night.SetStyle(widget.ClassTextIndicator, widget.StateAlarm, widget.Style{FillColor: red})
+
+	widget.SetTheme(day)
+	text := widget.NewThemedTextIndicator(image.Point{20, 20}, nil)
+	text.SetClock(clock)
+	text.AddThemeState("", widget.StateNormal)
+	text.AddThemeState("", widget.StateAlarm)
+	text.SetBlink(1, 0, 250*time.Millisecond, 250*time.Millisecond)
+	text.SetState(1)
+
+	widget.SetTheme(night)
+	text.Update()
+	if got := text.Render().RGBAAt(10, 10); got != blue {
+		t.Errorf("text indicator off phase after theme switch: %v, want %v", got, blue)
+	}
+}
+
+func TestThemeSwitch(t *testing.T) {
+	day := widget.NewTheme("day", color.White)
+	day.SetStyle(widget.ClassButton, widget.StateNormal,
+		widget.Style{FillColor: color.RGBA{200, 200, 200, 255}, TextSize: 10})
+	night := widget.NewTheme("night", color.Black)
+	night.SetStyle(widget.ClassButton, widget.StateNormal,
+		widget.Style{FillColor: color.RGBA{40, 40, 40, 255}, TextSize: 10})
+	night.SetStyle("button.danger", widget.StateNormal,
+		widget.Style{FillColor: color.RGBA{200, 0, 0, 255}, TextSize: 10})
+	defer widget.SetTheme(nil)
+
+	widget.SetTheme(day)
+	button := widget.NewThemedButton(image.Point{40, 20}, "", nil)
+	danger := widget.NewThemedButton(image.Point{40, 20}, "", nil)
+	danger.SetClass("button.danger")
+
+	if got := button.Render().RGBAAt(20, 2); got != (color.RGBA{200, 200, 200, 255}) {
+		t.Fatalf("day theme: %v", got)
+	}
+	// В дневной теме нет button.danger, берется стиль button
+	if got := danger.Render().RGBAAt(20, 2); got != (color.RGBA{200, 200, 200, 255}) {
+		t.Fatalf("day theme, danger class: %v", got)
+	}
+
+	widget.SetTheme(night)
+	for _, w := range []*widget.Button{button, danger} {
+		w.Update()
+		if !w.Updated() {
+			t.Fatalf("button is not updated after theme switch")
+		}
+	}
+	if got := button.Render().RGBAAt(20, 2); got != (color.RGBA{40, 40, 40, 255}) {
+		t.Fatalf("night theme: %v", got)
+	}
+	if got := danger.Render().RGBAAt(20, 2); got != (color.RGBA{200, 0, 0, 255}) {
+		t.Fatalf("night theme, danger class: %v", got)
+	}
+
+	// Изменение не текущей темы не перерисовывает виджеты
+	day.SetStyle(widget.ClassButton, widget.StateNormal,
+		widget.Style{FillColor: color.RGBA{220, 220, 220, 255}, TextSize: 10})
+	button.Update()
+	if button.Updated() {
+		t.Errorf("button is updated after change of inactive theme")
+	}
+
+	night.SetStyle(widget.ClassButton, widget.StateNormal,
+		widget.Style{FillColor: color.RGBA{60, 60, 60, 255}, TextSize: 10})
+	button.Update()
+	if !button.Updated() {
+		t.Errorf("button is not updated after change of current theme")
+	}
+
+	// Кнопка в фокусе рисуется стилем StateFocused
+	night.SetStyle(widget.ClassButton, widget.StateFocused,
+		widget.Style{FillColor: color.RGBA{0, 0, 160, 255}, TextSize: 10})
+	button.SetFocused(true)
+	if got := button.Render().RGBAAt(20, 2); got != (color.RGBA{0, 0, 160, 255}) {
+		t.Errorf("focused button: %v", got)
+	}
+	button.SetFocused(false)
+	if got := button.Render().RGBAAt(20, 2); got != (color.RGBA{60, 60, 60, 255}) {
+		t.Errorf("button after focus loss: %v", got)
+	}
+}
+
+func TestButtonDisable(t *testing.T) {
+	clicked := false
+	button := widget.NewButton(&widget.ButtonParam{
+		Size:             image.Point{40, 20},
+		OnClick:          func() { clicked = true },
+		ReleaseFillColor: color.RGBA{0, 0, 200, 255},
+	}, nil)
+
+	button.Disable()
+	if !button.Updated() {
+		t.Fatalf("button is not updated after Disable")
+	}
+	if got := button.Render().RGBAAt(20, 10); got.R != got.G || got.G != got.B {
+		t.Errorf("disabled button is not grey: %v", got)
+	}
+
+	button.Tap(image.Point{})
+	button.Release(image.Point{})
+	if clicked {
+		t.Errorf("disabled button fired OnClick")
+	}
+
+	button.Enable()
+	if got := button.Render().RGBAAt(20, 10); got != (color.RGBA{0, 0, 200, 255}) {
+		t.Errorf("enabled button: %v", got)
+	}
+}
+
+func TestButtonToggle(t *testing.T) {
+	on := color.RGBA{0, 200, 0, 255}
+	off := color.RGBA{200, 0, 0, 255}
+	toggled := make(chan bool, 4)
+
+	button := widget.NewButton(&widget.ButtonParam{
+		Size:             image.Point{40, 20},
+		Mode:             widget.ButtonToggle,
+		ReleaseFillColor: off,
+		PressFillColor:   off,
+		CheckedFillColor: on,
+		OnToggle:         func(checked bool) { toggled <- checked },
+	}, nil)
+
+	now := time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC)
+	clock := anim.NewClock(now)
+	button.SetClock(clock)
+	click := func() {
+		button.Tap(image.Point{})
+		button.Release(image.Point{})
+		now = now.Add(time.Second)
+		clock.Tick(now)
+		button.Update()
+	}
+
+	click()
+	if !button.Checked() || <-toggled != true {
+		t.Fatalf("toggle button is not checked after click")
+	}
+	if got := button.Render().RGBAAt(20, 10); got != on {
+		t.Errorf("checked button: %v, want %v", got, on)
+	}
+
+	click()
+	if button.Checked() || <-toggled != false {
+		t.Fatalf("toggle button is checked after second click")
+	}
+
+	// Состояние из источника не меняется нажатием
+	plc := false
+	button.CheckedSource = func() bool { return plc }
+	click()
+	if button.Checked() || <-toggled != true {
+		t.Errorf("button with CheckedSource changed state by click")
+	}
+	plc = true
+	button.Update()
+	if !button.Checked() {
+		t.Errorf("button does not follow CheckedSource")
+	}
+}
+
+// Ожидает, пока счетчик, увеличиваемый в горутинах OnClick, достигнет want
+func waitCount(t *testing.T, n *atomic.Int32, want int32) {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for n.Load() != want && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	if got := n.Load(); got != want {
+		t.Fatalf("clicks = %d, want %d", got, want)
+	}
+}
+
+func TestButtonRepeat(t *testing.T) {
+	h := sguitest.New(t, image.Point{40, 20})
+	screen := h.NewScreen()
+
+	var clicks atomic.Int32
+	var events []string
+	button := widget.NewButton(&widget.ButtonParam{
+		Size:           image.Point{40, 20},
+		Mode:           widget.ButtonRepeat,
+		RepeatDelay:    100 * time.Millisecond,
+		RepeatInterval: 40 * time.Millisecond,
+		OnClick:        func() { clicks.Add(1) },
+		OnPress:        func() { events = append(events, "press") },
+		OnRelease:      func() { events = append(events, "release") },
+	}, nil)
+	screen.AddWidget(0, 0, button)
+
+	h.Tap(10, 10)
+	waitCount(t, &clicks, 1)
+
+	// Повторы в 100, 140 и 180 мс
+	h.Advance(200 * time.Millisecond)
+	waitCount(t, &clicks, 4)
+
+	h.Release(10, 10)
+	h.Advance(200 * time.Millisecond)
+	waitCount(t, &clicks, 4)
+
+	if len(events) != 2 || events[0] != "press" || events[1] != "release" {
+		t.Errorf("events = %v, want [press release]", events)
+	}
+}
+
+func TestButtonHoldConfirm(t *testing.T) {
+	h := sguitest.New(t, image.Point{100, 20})
+	screen := h.NewScreen()
+
+	release := color.RGBA{200, 200, 200, 255}
+	hold := color.RGBA{0, 200, 0, 255}
+
+	var clicks atomic.Int32
+	button := widget.NewButton(&widget.ButtonParam{
+		Size:             image.Point{100, 20},
+		Mode:             widget.ButtonHoldConfirm,
+		HoldDuration:     time.Second,
+		ReleaseFillColor: release,
+		PressFillColor:   release,
+		HoldFillColor:    hold,
+		OnClick:          func() { clicks.Add(1) },
+	}, nil)
+	screen.AddWidget(0, 0, button)
+
+	// Отпускание до окончания удержания не подтверждает команду
+	h.Tap(50, 10)
+	h.Advance(500 * time.Millisecond)
+	if got := h.Display.RGBAAt(30, 10); got != hold {
+		t.Errorf("progress at 50%%: pixel 30 = %v, want %v", got, hold)
+	}
+	if got := h.Display.RGBAAt(70, 10); got != release {
+		t.Errorf("progress at 50%%: pixel 70 = %v, want %v", got, release)
+	}
+	h.Release(50, 10)
+	h.Advance(time.Second)
+	waitCount(t, &clicks, 0)
+
+	h.Tap(50, 10)
+	h.Advance(time.Second)
+	waitCount(t, &clicks, 1)
+	if got := h.Display.RGBAAt(95, 10); got != hold {
+		t.Errorf("confirmed: pixel 95 = %v, want %v", got, hold)
+	}
+	h.Release(50, 10)
+}
+
+func TestButtonIcon(t *testing.T) {
+	icon := image.NewRGBA(image.Rect(0, 0, 10, 10))
+	draw.Draw(icon, icon.Rect, image.NewUniform(color.Black), image.Point{}, draw.Src)
+	pressedIcon := image.NewRGBA(image.Rect(0, 0, 20, 10))
+	draw.Draw(pressedIcon, pressedIcon.Rect, image.NewUniform(color.Black), image.Point{}, draw.Src)
+
+	fill := color.RGBA{200, 200, 200, 255}
+	red := color.RGBA{255, 0, 0, 255}
+	button := widget.NewButton(&widget.ButtonParam{
+		Size:             image.Point{40, 20},
+		ReleaseFillColor: fill,
+		PressFillColor:   fill,
+		Text:             "text",
+		TextSize:         12,
+		TextColor:        red,
+		Icon:             icon,
+		PressedIcon:      pressedIcon,
+		IconPlacement:    widget.IconOnly,
+		IconTint:         true,
+	}, nil)
+
+	img := button.Render()
+	if got := img.RGBAAt(20, 10); got != red {
+		t.Errorf("tinted icon: %v, want %v", got, red)
+	}
+	if got := img.RGBAAt(12, 10); got != fill {
+		t.Errorf("outside icon: %v, want %v", got, fill)
+	}
+
+	now := time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC)
+	clock := anim.NewClock(now)
+	button.SetClock(clock)
+	button.Tap(image.Point{})
+	if got := button.Render().RGBAAt(12, 10); got != red {
+		t.Errorf("pressed icon: %v, want %v", got, red)
+	}
+	button.Release(image.Point{})
+
+	// Иконка слева от текста смещена влево от центра
+	button.SetIconPlacement(widget.IconLeft, false)
+	clock.Tick(now.Add(time.Second))
+	img = button.Render()
+	if got := img.RGBAAt(20, 10); got == (color.RGBA{0, 0, 0, 255}) {
+		t.Errorf("icon on the left is drawn at the center")
+	}
+}
 
-			fname := fmt.Sprintf("%s.png", tt.name)
-			f, err := os.Create(fname)
-			if err != nil {
-				return
+func TestLabelLayout(t *testing.T) {
+	label := widget.NewLabel(&widget.LabelParam{
+		Size:      image.Point{100, 60},
+		Text:      "Давление в напорном трубопроводе превышает норму",
+		TextSize:  14,
+		TextColor: color.Black,
+		TextLayout: text2img.Layout{
+			Wrap:     true,
+			Align:    text2img.AlignLeft,
+			Overflow: text2img.OverflowEllipsis,
+		},
+	}, nil)
+
+	// Текст переносится и не заходит в отступы
+	img := label.Render()
+	rows := map[int]bool{}
+	for y := 0; y < 60; y++ {
+		for x := 0; x < 100; x++ {
+			if img.RGBAAt(x, y).A == 0 {
+				continue
+			}
+			if x < 4 || x >= 96 || y < 4 || y >= 56 {
+				t.Fatalf("text in padding at %d, %d", x, y)
 			}
-			defer f.Close()
+			rows[y/20] = true
+		}
+	}
+	if len(rows) < 2 {
+		t.Errorf("text is not wrapped: %v", rows)
+	}
+}
 
-			err = png.Encode(f, img)
-			if err != nil {
-				return
+func TestLabelMarkup(t *testing.T) {
+	label := widget.NewLabel(&widget.LabelParam{
+		Size:      image.Point{160, 40},
+		TextSize:  16,
+		TextColor: color.Black,
+	}, nil)
+
+	if err := label.SetMarkup("[b]x[/color]"); err == nil {
+		t.Error("bad markup accepted")
+	}
+	if err := label.SetMarkup("Насос [color=#ff0000]**авария**[/color]"); err != nil {
+		t.Fatal(err)
+	}
+
+	img := label.Render()
+	reds := 0
+	for i := 0; i < len(img.Pix); i += 4 {
+		if img.Pix[i] > 200 && img.Pix[i+1] < 50 && img.Pix[i+3] > 200 {
+			reds++
+		}
+	}
+	if reds == 0 {
+		t.Error("colored span is not drawn")
+	}
+}
+
+func TestTextBaseline(t *testing.T) {
+	// Нижний край букв без выносных элементов
+	bottom := func(img *image.RGBA) int {
+		y := -1
+		for py := img.Rect.Min.Y; py < img.Rect.Max.Y; py++ {
+			for px := img.Rect.Min.X; px < img.Rect.Max.X; px++ {
+				if img.RGBAAt(px, py).A > 128 {
+					y = py
+				}
 			}
+		}
+		return y
+	}
 
+	layout := text2img.Layout{VAlign: text2img.VAlignBaseline, Baseline: 30}
+	var lines []int
+	for _, size := range []float64{12, 20, 28} {
+		label := widget.NewLabel(&widget.LabelParam{
+			Size:       image.Point{80, 40},
+			Text:       "НП",
+			TextSize:   size,
+			TextColor:  color.Black,
+			TextLayout: layout,
+		}, nil)
+		lines = append(lines, bottom(label.Render()))
+
+		indicator := widget.NewTextIndicator(widget.TextIndicatorParam{
+			Size:       image.Point{80, 40},
+			TextLayout: layout,
 		})
+		indicator.AddState("НП", size, color.Black, nil, nil)
+		lines = append(lines, bottom(indicator.Render()))
+	}
+
+	for _, y := range lines {
+		if y != 29 {
+			t.Errorf("text bottoms %v, want all on baseline 30", lines)
+			break
+		}
+	}
+}
+
+func TestImageSource(t *testing.T) {
+	red := image.NewUniform(color.RGBA{255, 0, 0, 255})
+	green := image.NewUniform(color.RGBA{0, 255, 0, 255})
+	srcRed := image.NewRGBA(image.Rect(0, 0, 4, 4))
+	draw.Draw(srcRed, srcRed.Rect, red, image.Point{}, draw.Src)
+	srcGreen := image.NewRGBA(image.Rect(0, 0, 4, 4))
+	draw.Draw(srcGreen, srcGreen.Rect, green, image.Point{}, draw.Src)
+
+	current := image.Image(srcRed)
+	w := widget.NewImage(image.Point{20, 10}, nil, painter.ScaleStretch)
+	w.ImageSource = func() image.Image { return current }
+
+	w.Update()
+	first := w.Render()
+	if got := first.RGBAAt(10, 5); got != (color.RGBA{255, 0, 0, 255}) {
+		t.Errorf("red image: %v", got)
+	}
+
+	w.Update()
+	if w.Updated() {
+		t.Errorf("image is updated without source change")
+	}
+
+	current = srcGreen
+	w.Update()
+	if !w.Updated() {
+		t.Fatalf("image is not updated after source change")
+	}
+	if got := w.Render().RGBAAt(10, 5); got != (color.RGBA{0, 255, 0, 255}) {
+		t.Errorf("green image: %v", got)
+	}
+
+	// Рендер прежнего изображения берется из кэша
+	current = srcRed
+	w.Update()
+	if w.Render() != first {
+		t.Errorf("render of the same image is not cached")
+	}
+}
+
+func TestAnimated(t *testing.T) {
+	h := sguitest.New(t, image.Point{10, 10})
+	screen := h.NewScreen()
+
+	colors := []color.RGBA{{255, 0, 0, 255}, {0, 255, 0, 255}, {0, 0, 255, 255}}
+	sheet := image.NewRGBA(image.Rect(0, 0, 30, 10))
+	for i, c := range colors {
+		draw.Draw(sheet, image.Rect(i*10, 0, i*10+10, 10), image.NewUniform(c), image.Point{}, draw.Src)
+	}
+	running := widget.ClipFromSpriteSheet(sheet, image.Point{10, 10}, 0, 100*time.Millisecond)
+	stopped := widget.ClipFromSpriteSheet(sheet, image.Point{10, 10}, 1, time.Second)
+	if len(running.Frames) != 3 || len(stopped.Frames) != 1 {
+		t.Fatalf("sprite sheet frames: %d %d", len(running.Frames), len(stopped.Frames))
+	}
+
+	state := 0
+	w := widget.NewAnimated(image.Point{10, 10}, painter.ScaleStretch, running, stopped)
+	w.StateSource = func() int { return state }
+	screen.AddWidget(0, 0, w)
+
+	check := func(name string, want color.RGBA) {
+		t.Helper()
+		if got := h.Display.RGBAAt(5, 5); got != want {
+			t.Errorf("%s: %v, want %v", name, got, want)
+		}
+	}
+
+	h.Frames(1)
+	check("start", colors[0])
+	h.Advance(100 * time.Millisecond)
+	check("100ms", colors[1])
+	h.Advance(200 * time.Millisecond)
+	check("loop", colors[0])
+
+	w.Pause()
+	h.Advance(time.Second)
+	check("paused", colors[0])
+	w.Play()
+	h.Advance(100 * time.Millisecond)
+	check("resumed", colors[1])
+
+	state = 1
+	h.Frames(1)
+	check("state 1", colors[0])
+}
+
+func TestClipFromGIF(t *testing.T) {
+	palette := color.Palette{color.Transparent, color.RGBA{255, 0, 0, 255}, color.RGBA{0, 255, 0, 255}}
+	full := image.NewPaletted(image.Rect(0, 0, 4, 4), palette)
+	draw.Draw(full, full.Rect, image.NewUniform(palette[1]), image.Point{}, draw.Src)
+	// Второй кадр закрашивает только часть изображения
+	part := image.NewPaletted(image.Rect(0, 0, 2, 2), palette)
+	draw.Draw(part, part.Rect, image.NewUniform(palette[2]), image.Point{}, draw.Src)
+
+	var buf bytes.Buffer
+	err := gif.EncodeAll(&buf, &gif.GIF{
+		Image:     []*image.Paletted{full, part},
+		Delay:     []int{5, 0},
+		LoopCount: -1,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	clip, err := widget.ClipFromGIF(buf.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(clip.Frames) != 2 || clip.Loops != 1 {
+		t.Fatalf("frames %d, loops %d", len(clip.Frames), clip.Loops)
+	}
+	if clip.Delays[0] != 50*time.Millisecond || clip.Delays[1] != widget.DefaultFrameDelay {
+		t.Errorf("delays %v", clip.Delays)
+	}
+
+	second := clip.Frames[1]
+	if got := second.At(0, 0); got != palette[2] {
+		t.Errorf("second frame, changed part: %v", got)
+	}
+	if got := second.At(3, 3); got != palette[1] {
+		t.Errorf("second frame, kept part: %v", got)
+	}
+}
+
+func TestCanvas(t *testing.T) {
+	h := sguitest.New(t, image.Point{40, 40})
+	screen := h.NewScreen()
+
+	red := color.RGBA{255, 0, 0, 255}
+	value := 0.0
+	shown := 0.0
+	draws := 0
+
+	// Стрелка прибора: горизонтальная линия длиной value
+	gauge := widget.NewCanvas(image.Point{40, 40}, func(ctx *widget.DrawContext) {
+		draws++
+		shown = value
+		ctx.Line(0, 20, value, 20, painter.Stroke{Color: red, Width: 4})
+	})
+	gauge.Changed = func() bool { return value != shown }
+	screen.AddWidget(0, 0, gauge)
+
+	h.Frames(3)
+	if draws != 1 {
+		t.Errorf("draw called %d times without changes, want 1", draws)
+	}
+
+	value = 30
+	h.Frames(1)
+	if draws != 2 {
+		t.Errorf("draw called %d times after change, want 2", draws)
+	}
+	if got := h.Display.RGBAAt(25, 20); got != red {
+		t.Errorf("gauge needle: %v", got)
+	}
+
+	// Смена фона повторяет записанные команды
+	blue := color.RGBA{0, 0, 255, 255}
+	gauge.SetBackground(blue)
+	h.Frames(1)
+	if draws != 2 {
+		t.Errorf("draw called on background change")
+	}
+	if got := h.Display.RGBAAt(25, 20); got != red {
+		t.Errorf("gauge needle over background: %v", got)
+	}
+	if got := h.Display.RGBAAt(25, 5); got != blue {
+		t.Errorf("canvas background: %v", got)
+	}
+
+	gauge.Disable()
+	h.Frames(1)
+	if draws != 2 {
+		t.Errorf("draw called on disable")
+	}
+	if got := h.Display.RGBAAt(25, 20); got.R != got.G {
+		t.Errorf("disabled canvas is not grey: %v", got)
+	}
+
+	// Приглушенный рендер не пересчитывается без изменений холста
+	if gauge.Render() != gauge.Render() {
+		t.Errorf("disabled render is not cached")
+	}
+	value = 10
+	h.Frames(1)
+	if got := h.Display.RGBAAt(25, 20); got == h.Display.RGBAAt(5, 20) {
+		t.Errorf("disabled canvas is not redrawn after change: %v", got)
+	}
+}
+
+func TestCanvasRetained(t *testing.T) {
+	red := color.RGBA{255, 0, 0, 255}
+	points := []painter.Point{{X: 0, Y: 5}, {X: 20, Y: 5}}
+	path := &painter.Path{}
+	path.MoveTo(0, 15)
+	path.LineTo(20, 15)
+	stroke := painter.Stroke{Color: red, Width: 2, Dash: []float64{100, 1}}
+
+	canvas := widget.NewCanvas(image.Point{20, 20}, func(ctx *widget.DrawContext) {
+		ctx.Polyline(points, stroke)
+		ctx.Path(path, nil, stroke)
+	})
+	canvas.Render()
+
+	// Изменение данных после рисования не влияет на повтор команд
+	points[1] = painter.Point{X: 0, Y: 5}
+	path.LineTo(0, 0)
+	stroke.Dash[0] = 1
+	canvas.SetBackground(color.White)
+	img := canvas.Render()
+	for _, p := range []image.Point{{15, 5}, {15, 15}} {
+		if got := img.RGBAAt(p.X, p.Y); got != red {
+			t.Errorf("replayed line at %v: %v", p, got)
+		}
+	}
+}
+
+func TestRectangle(t *testing.T) {
+	red := color.RGBA{255, 0, 0, 255}
+	rect := widget.NewRectangle(image.Point{10, 10}, red, nil)
+	if got := rect.Render().RGBAAt(5, 5); got != red {
+		t.Errorf("rectangle: %v", got)
+	}
+
+	rect.Disable()
+	img := rect.Render()
+	if got := img.RGBAAt(5, 5); got.R != got.G {
+		t.Errorf("disabled rectangle is not grey: %v", got)
+	}
+	if rect.Render() != img {
+		t.Errorf("disabled render is not cached")
 	}
 }